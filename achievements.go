@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+
+	"github.com/yongtenglei/dino/assets"
+	"github.com/yongtenglei/dino/sim"
+	"github.com/yongtenglei/dino/storage"
+)
+
+const (
+	achievementsFile = "achievements.json"
+
+	toastFrames = 180
+)
+
+// achievement counts one kind of event towards a goal. They are defined
+// in assets/achievements.json, so adding one takes no code as long as the
+// events and filters below can tell it.
+type achievement struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Event is the name of the event counted, see eventNames.
+	Event string `json:"event"`
+	Goal  int    `json:"goal"`
+	// Lifetime counts over every run, otherwise the goal takes one run.
+	Lifetime bool `json:"lifetime,omitempty"`
+	// Without counts an event only if none of this one happened since the
+	// previous counted one, or since the start of the run.
+	Without string `json:"without,omitempty"`
+
+	// The rest only count cleared hazards: those whose kind name ends with
+	// Obstacle ("bird" is any bird), at least MinWidth wide, passed while
+	// ducking or after at least Jumps jumps.
+	Obstacle string `json:"obstacle,omitempty"`
+	MinWidth int    `json:"minWidth,omitempty"`
+	Ducking  bool   `json:"ducking,omitempty"`
+	Jumps    int    `json:"jumps,omitempty"`
+
+	event   sim.EventKind
+	without sim.EventKind
+}
+
+var achievements = loadAchievements(assets.Achievements)
+
+func loadAchievements(data []byte) []achievement {
+	var list []achievement
+	if err := json.Unmarshal(data, &list); err != nil {
+		log.Fatalf("reading achievements: %v", err)
+	}
+	for i := range list {
+		a := &list[i]
+		var ok bool
+		if a.event, ok = sim.FindEvent(a.Event); !ok {
+			log.Fatalf("achievement %s: unknown event %q", a.ID, a.Event)
+		}
+		a.without = -1
+		if a.Without != "" {
+			if a.without, ok = sim.FindEvent(a.Without); !ok {
+				log.Fatalf("achievement %s: unknown event %q", a.ID, a.Without)
+			}
+		}
+		a.Goal = max(a.Goal, 1)
+	}
+	return list
+}
+
+// counts reports whether the event e of p counts towards a.
+func (a *achievement) counts(p *sim.Runner, e sim.Event) bool {
+	if e.Kind != a.event {
+		return false
+	}
+	if e.Kind != sim.EventCleared {
+		return true
+	}
+	name := sim.ObstacleKinds[e.Value].Name
+	return strings.HasSuffix(name, a.Obstacle) &&
+		e.Width >= a.MinWidth &&
+		(!a.Ducking || p.IsDucking) &&
+		p.JumpCount >= a.Jumps
+}
+
+// achievementProgress is what is saved of the achievements.
+type achievementProgress struct {
+	// Unlocked maps the id of every unlocked achievement to the day it was.
+	Unlocked map[string]string `json:"unlocked"`
+	// Progress is the count of a lifetime achievement so far, or the best
+	// count in one run of the others.
+	Progress map[string]int `json:"progress"`
+}
+
+// achievementTracker follows the events of player one's runs, unlocking
+// achievements and announcing them with a toast.
+type achievementTracker struct {
+	achievementProgress
+
+	// run is the runner the counts below are of
+	run     *sim.Runner
+	counts  []int
+	tainted []bool
+
+	toasts     []string
+	toastTicks int
+}
+
+func loadAchievementTracker() *achievementTracker {
+	t := &achievementTracker{}
+	if err := storage.LoadJSON(achievementsFile, &t.achievementProgress); err != nil {
+		log.Printf("loading achievements: %v", err)
+	}
+	if t.Unlocked == nil {
+		t.Unlocked = map[string]string{}
+	}
+	if t.Progress == nil {
+		t.Progress = map[string]int{}
+	}
+	return t
+}
+
+func (t *achievementTracker) save() error {
+	return storage.SaveJSON(achievementsFile, &t.achievementProgress)
+}
+
+// onEvent counts the event e of p, a runner of player one.
+func (t *achievementTracker) onEvent(p *sim.Runner, e sim.Event) {
+	if p != t.run {
+		t.run = p
+		t.counts = make([]int, len(achievements))
+		t.tainted = make([]bool, len(achievements))
+	}
+	unlocked := false
+	for i := range achievements {
+		a := &achievements[i]
+		if _, done := t.Unlocked[a.ID]; done {
+			continue
+		}
+		if e.Kind == a.without {
+			t.tainted[i] = true
+		}
+		if !a.counts(p, e) {
+			continue
+		}
+		if t.tainted[i] {
+			t.tainted[i] = false
+			continue
+		}
+		t.counts[i]++
+		n := t.counts[i]
+		if a.Lifetime {
+			n = t.Progress[a.ID] + 1
+		}
+		t.Progress[a.ID] = max(t.Progress[a.ID], n)
+		if n >= a.Goal {
+			t.Unlocked[a.ID] = sim.DayKey(time.Now())
+			delete(t.Progress, a.ID)
+			t.toasts = append(t.toasts, a.Name)
+			unlocked = true
+		}
+	}
+	// progress is saved with an unlock or when the run ends
+	if unlocked || e.Kind == sim.EventDied || e.Kind == sim.EventFinished {
+		if err := t.save(); err != nil {
+			log.Printf("saving achievements: %v", err)
+		}
+	}
+}
+
+// update shows each toast for a while, one after the other.
+func (t *achievementTracker) update() {
+	if len(t.toasts) == 0 {
+		return
+	}
+	t.toastTicks++
+	if t.toastTicks >= toastFrames {
+		t.toastTicks = 0
+		t.toasts = t.toasts[1:]
+	}
+}
+
+// drawToast announces the latest unlock at the top of the screen.
+func (t *achievementTracker) drawToast(dst *ebiten.Image, ink color.Color) {
+	if len(t.toasts) == 0 {
+		return
+	}
+	drawCentered(dst, "Achievement unlocked: "+t.toasts[0], 20, ink)
+}
+
+// updateAchievements runs the achievements screen: UP/DOWN scroll and ESC
+// goes back to the start screen.
+func (g *Game) updateAchievements() {
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyEscape):
+		g.menu = menuNone
+	case inpututil.IsKeyJustPressed(ebiten.KeyUp):
+		g.achievementCursor = max(g.achievementCursor-1, 0)
+	case inpututil.IsKeyJustPressed(ebiten.KeyDown):
+		g.achievementCursor = min(g.achievementCursor+1, max(len(achievements)-achievementRows, 0))
+	}
+}
+
+// achievementRows is how many achievements fit on the screen.
+const achievementRows = 10
+
+func (g *Game) drawAchievements(screen *ebiten.Image) {
+	screen.Fill(color.RGBA{0x30, 0x30, 0x40, 0xff})
+
+	t := g.achievements
+	drawCentered(screen, "ACHIEVEMENTS", 60, color.White)
+	drawCentered(screen, fmt.Sprintf("%d of %d unlocked", len(t.Unlocked), len(achievements)), 90, color.White)
+	end := min(g.achievementCursor+achievementRows, len(achievements))
+	for i, a := range achievements[g.achievementCursor:end] {
+		var status string
+		if day, ok := t.Unlocked[a.ID]; ok {
+			status = "unlocked " + day
+		} else {
+			status = fmt.Sprintf("%d/%d", t.Progress[a.ID], a.Goal)
+			if !a.Lifetime {
+				status += " best run"
+			}
+		}
+		y := float64(130 + i*40)
+		drawCentered(screen, fmt.Sprintf("%-20s %24s", a.Name, status), y, color.White)
+		drawCentered(screen, a.Description, y+16, gray)
+	}
+	drawCentered(screen, "UP/DOWN: Scroll | ESC: Back", float64(sim.ScreenHeight-40), gray)
+}
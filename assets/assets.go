@@ -0,0 +1,30 @@
+// Package assets holds the files built into the game: the sprite sheet,
+// the sounds and the data files.
+package assets
+
+import _ "embed"
+
+//go:embed sprite.png
+var Sprite []byte
+
+//go:embed jump.wav
+var JumpWav []byte
+
+//go:embed die.wav
+var DieWav []byte
+
+//go:embed point.wav
+var PointWav []byte
+
+//go:embed achievements.json
+var Achievements []byte
+
+// Logo is the title of the game in ASCII art.
+var Logo = []string{
+	"    ____     ____   _   __   ____ ",
+	"   / __ \\   /  _/  / | / /  / __ \\",
+	"  / / / /   / /   /  |/ /  / / / /",
+	" / /_/ /  _/ /   / /|  /  / /_/ / ",
+	"/_____/  /___/  /_/ |_/   \\____/  ",
+	"                                 ",
+}
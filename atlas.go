@@ -0,0 +1,140 @@
+package main
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	text "github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/yongtenglei/dino/sim"
+)
+
+// pebbleRects are loose bits of the ground strip.
+var pebbleRects = []image.Rectangle{
+	image.Rect(14, 120, 14+8, 120+2),
+	image.Rect(112, 118, 112+6, 118+2),
+	image.Rect(206, 120, 206+4, 120+2),
+	image.Rect(304, 118, 304+8, 118+2),
+}
+
+// horizonY is where the ground line runs.
+const horizonY = sim.ScreenHeight - sim.GroundHeight - 10
+
+// backgroundLayers are the parallax layers behind the course, back to
+// front. The sprite sheet has no mountains or hills, those are plain shapes.
+// Clouds go by with the course, see sim.Runner.Clouds.
+var backgroundLayers = []layerSpec{
+	{
+		shape: shapeMountain,
+		LayerSpec: sim.LayerSpec{
+			MinW:        200,
+			MaxW:        360,
+			MinH:        80,
+			MaxH:        150,
+			Speed:       0.1,
+			BottomMin:   horizonY,
+			BottomMax:   horizonY,
+			MaxItems:    4,
+			MinGap:      200,
+			SpawnChance: 0.01,
+		},
+		clr: color.RGBA{0xf0, 0xf0, 0xf0, 0xff},
+	},
+	{
+		shape: shapeHill,
+		LayerSpec: sim.LayerSpec{
+			MinW:        120,
+			MaxW:        240,
+			MinH:        20,
+			MaxH:        45,
+			Speed:       0.2,
+			BottomMin:   horizonY,
+			BottomMax:   horizonY,
+			MaxItems:    5,
+			MinGap:      160,
+			SpawnChance: 0.01,
+		},
+		clr: color.RGBA{0xe4, 0xe4, 0xe4, 0xff},
+	},
+	{
+		LayerSpec: sim.LayerSpec{
+			Rects:       pebbleRects,
+			Speed:       1,
+			BottomMin:   horizonY + 4,
+			BottomMax:   horizonY + 20,
+			MaxItems:    6,
+			MinGap:      60,
+			SpawnChance: 0.05,
+		},
+	},
+}
+
+type sprites struct {
+	dinoStand   []*ebiten.Image
+	dinoRunning []*ebiten.Image
+	dinoDead    []*ebiten.Image
+	dinoDuck    []*ebiten.Image
+	ground      *ebiten.Image
+	sheet       *ebiten.Image
+	// subs are the regions of the sheet cut so far
+	subs map[image.Rectangle]*ebiten.Image
+}
+
+func subImages(sheet *ebiten.Image, rects []image.Rectangle) []*ebiten.Image {
+	imgs := make([]*ebiten.Image, len(rects))
+	for i, r := range rects {
+		imgs[i] = sheet.SubImage(r).(*ebiten.Image)
+	}
+	return imgs
+}
+
+func newSprites(sheet *ebiten.Image) *sprites {
+	return &sprites{
+		ground:      sheet.SubImage(sim.GroundRect).(*ebiten.Image),
+		sheet:       sheet,
+		dinoStand:   subImages(sheet, sim.DinoStandRects),
+		dinoRunning: subImages(sheet, sim.DinoRunningRects),
+		dinoDead:    subImages(sheet, sim.DinoDeadRects),
+		dinoDuck:    subImages(sheet, sim.DinoDuckRects),
+		subs:        map[image.Rectangle]*ebiten.Image{},
+	}
+}
+
+// sub returns the region r of the sheet, cut on first use.
+func (s *sprites) sub(r image.Rectangle) *ebiten.Image {
+	img, ok := s.subs[r]
+	if !ok {
+		img = s.sheet.SubImage(r).(*ebiten.Image)
+		s.subs[r] = img
+	}
+	return img
+}
+
+// imageCanvas is the canvas of the window, entities draw on dst through it.
+type imageCanvas struct {
+	dst     *ebiten.Image
+	sprites *sprites
+}
+
+func (c imageCanvas) Sprite(src image.Rectangle, x, y float64) {
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(x, y)
+	c.dst.DrawImage(c.sprites.sub(src), op)
+}
+
+func (c imageCanvas) Disc(x, y, r float64, clr color.Color) {
+	vector.DrawFilledCircle(c.dst, float32(x), float32(y), float32(r), clr, true)
+}
+
+func (c imageCanvas) Ring(x, y, r, width float64, clr color.Color) {
+	vector.StrokeCircle(c.dst, float32(x), float32(y), float32(r), float32(width), clr, true)
+}
+
+func (c imageCanvas) Glyph(s string, x, y float64, clr color.Color) {
+	op := &text.DrawOptions{}
+	op.GeoM.Translate(x-float64(len(s)*7)/2, y-7)
+	op.ColorScale.ScaleWithColor(clr)
+	text.Draw(c.dst, s, face, op)
+}
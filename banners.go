@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	text "github.com/hajimehoshi/ebiten/v2/text/v2"
+
+	"github.com/yongtenglei/dino/sim"
+)
+
+// drawBanners draws the blinking speed up and power-up notices of one runner.
+func (g *Game) drawBanners(dst *ebiten.Image, p *sim.Runner) {
+	if p.Dead {
+		return
+	}
+	if b := &p.Banners.SpeedUp; b.Showing() {
+		levelText := fmt.Sprintf("LEVEL %d", p.SpeedLevel)
+		speedUpX := float64(sim.ScreenWidth)/2 - float64(len(b.Text)*7/2)
+		levelX := float64(sim.ScreenWidth)/2 - float64(len(levelText)*7/2)
+		speedUpY := float64(sim.ScreenHeight)/2 - 50
+		levelY := float64(sim.ScreenHeight)/2 - 30
+		drawSpeedUpOpts := &text.DrawOptions{}
+		drawSpeedUpOpts.GeoM.Translate(speedUpX, speedUpY)
+		drawSpeedUpOpts.ColorScale.ScaleWithColor(g.night.ink())
+		text.Draw(dst, b.Text, face, drawSpeedUpOpts)
+		drawLevelOpts := &text.DrawOptions{}
+		drawLevelOpts.GeoM.Translate(levelX, levelY)
+		drawLevelOpts.ColorScale.ScaleWithColor(g.night.ink())
+		text.Draw(dst, levelText, face, drawLevelOpts)
+	}
+
+	if b := &p.Banners.PowerUp; b.Showing() {
+		bannerX := float64(sim.ScreenWidth)/2 - float64(len(b.Text)*7/2)
+		bannerY := float64(sim.ScreenHeight)/2 - 10
+		drawBannerOpts := &text.DrawOptions{}
+		drawBannerOpts.GeoM.Translate(bannerX, bannerY)
+		drawBannerOpts.ColorScale.ScaleWithColor(g.night.ink())
+		text.Draw(dst, b.Text, face, drawBannerOpts)
+	}
+}
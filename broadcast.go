@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/yongtenglei/dino/sim"
+)
+
+const (
+	// defaultWatchDelay is how many ticks a spectator stays behind the
+	// player, so a late packet does not stall the picture.
+	defaultWatchDelay = 10
+)
+
+// Message types of the spectator stream. As with the LAN protocol, every
+// message is one JSON object per line.
+const (
+	streamRun  = "run"  // a run is going on: Replay holds its seed and every input so far
+	streamTick = "tick" // the player's controls for one more tick: Bits
+	streamOver = "over" // the run ended with Score
+)
+
+type streamMessage struct {
+	Type   string      `json:"type"`
+	Replay *sim.Replay `json:"replay,omitempty"`
+	Bits   int         `json:"bits,omitempty"`
+	Over   bool        `json:"over,omitempty"`
+	Score  int         `json:"score,omitempty"`
+}
+
+// broadcaster publishes the local runs to spectators. It keeps its own copy
+// of the current run so late joiners can start from a snapshot.
+type broadcaster struct {
+	ln       net.Listener
+	mu       sync.Mutex
+	watchers map[*lanPeer]struct{}
+	run      *sim.Replay
+	over     bool
+}
+
+func startBroadcast(addr string) (*broadcaster, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	b := &broadcaster{ln: ln, watchers: map[*lanPeer]struct{}{}}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			b.add(conn)
+		}
+	}()
+	return b, nil
+}
+
+func (b *broadcaster) snapshot() streamMessage {
+	rep := *b.run
+	rep.Inputs = append([][2]int(nil), b.run.Inputs...)
+	return streamMessage{Type: streamRun, Replay: &rep, Over: b.over}
+}
+
+func (b *broadcaster) add(conn net.Conn) {
+	p := newLANPeer(0, conn)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.watchers[p] = struct{}{}
+	if b.run != nil {
+		p.send(b.snapshot())
+	}
+	// spectators never talk, a failed read means they are gone
+	go func() {
+		_, _ = bufio.NewReader(conn).ReadByte()
+		p.close()
+		b.mu.Lock()
+		delete(b.watchers, p)
+		b.mu.Unlock()
+	}()
+}
+
+func (b *broadcaster) publish(m streamMessage) {
+	for p := range b.watchers {
+		p.send(m)
+	}
+}
+
+func (b *broadcaster) startRun(run *sim.Replay) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.run = sim.NewReplay(run.Seed, run.ShieldRules())
+	b.over = false
+	b.publish(b.snapshot())
+}
+
+func (b *broadcaster) tick(in sim.Input) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.run == nil {
+		return
+	}
+	b.run.Record(in)
+	b.publish(streamMessage{Type: streamTick, Bits: sim.InputBits(in)})
+}
+
+func (b *broadcaster) end(score int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.run == nil {
+		return
+	}
+	b.run.Score = score
+	b.over = true
+	b.publish(streamMessage{Type: streamOver, Score: score})
+}
+
+// spectator is the watching side: it replays the stream into a runner,
+// delay ticks behind what it has received.
+type spectator struct {
+	addr     string
+	delay    int
+	incoming chan streamMessage
+	pending  []sim.Input
+	over     bool
+	lost     bool
+}
+
+// watchCommand implements `dino watch host:port`.
+func watchCommand(args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	delay := fs.Int("delay", defaultWatchDelay, "ticks to stay behind the player")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: dino watch [-delay TICKS] host:port")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	conn, err := net.Dial("tcp", fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	s := &spectator{addr: fs.Arg(0), delay: max(*delay, 0), incoming: make(chan streamMessage, lanIncomingBuffer)}
+	go func() {
+		dec := json.NewDecoder(bufio.NewReader(conn))
+		for {
+			var m streamMessage
+			if err := dec.Decode(&m); err != nil {
+				close(s.incoming)
+				return
+			}
+			s.incoming <- m
+		}
+	}()
+
+	game := newGame()
+	game.startScreen = false
+	game.mode = modeWatch
+	game.watch = s
+	runGame(game)
+	return nil
+}
+
+// catchUp plays inputs on p without publishing their events, as when
+// syncing from a snapshot.
+func catchUp(p *sim.Runner, inputs []sim.Input) {
+	for _, in := range inputs {
+		p.Update(in)
+	}
+}
+
+func (g *Game) updateWatch() {
+	s := g.watch
+	for drained := false; !drained; {
+		select {
+		case m, ok := <-s.incoming:
+			if !ok {
+				s.lost = true
+				drained = true
+				break
+			}
+			switch m.Type {
+			case streamRun:
+				p := m.Replay.NewRunner()
+				s.pending = s.pending[:0]
+				for _, span := range m.Replay.Inputs {
+					for range span[1] {
+						s.pending = append(s.pending, sim.BitsInput(span[0]))
+					}
+				}
+				// late joiners jump straight to a few ticks behind the player
+				if n := len(s.pending) - s.delay; n > 0 {
+					catchUp(p, s.pending[:n])
+					s.pending = append(s.pending[:0], s.pending[n:]...)
+				}
+				g.players = append(g.players[:0], p)
+				s.over = m.Over
+				g.gameOver = false
+			case streamTick:
+				s.pending = append(s.pending, sim.BitsInput(m.Bits))
+			case streamOver:
+				s.over = true
+			}
+		default:
+			drained = true
+		}
+	}
+
+	if len(g.players) == 0 {
+		return
+	}
+	p := g.players[0]
+	if p.Dead {
+		p.AnimateDead()
+		g.gameOver = true
+		return
+	}
+
+	// once the run is over nothing more arrives, so the delay is pointless
+	steps := 0
+	if len(s.pending) > s.delay || (s.over && len(s.pending) > 0) {
+		steps = 1
+	}
+	// fall back in step when the stream arrived in a burst
+	if len(s.pending) > 2*s.delay+1 {
+		steps = 2
+	}
+	for range steps {
+		p.Update(s.pending[0])
+		g.observe(p)
+		s.pending = s.pending[1:]
+	}
+	if p.Score > g.highScore {
+		g.highScore = p.Score
+	}
+}
+
+func (g *Game) drawWatch(screen *ebiten.Image) {
+	s := g.watch
+	if len(g.players) == 0 {
+		msg := fmt.Sprintf("Waiting for a run on %s...", s.addr)
+		if s.lost {
+			msg = "The player stopped broadcasting"
+		}
+		drawCentered(screen, msg, float64(sim.ScreenHeight)/2, g.night.ink())
+		return
+	}
+
+	p := g.players[0]
+	world := g.night.begin(screen)
+	g.drawWorld(world, p, defaultSkin)
+	g.night.end(screen)
+	g.drawHUD(screen, p, 10, 20, 0)
+	g.drawBanners(screen, p)
+
+	watchText := fmt.Sprintf("WATCHING %s", s.addr)
+	drawCentered(screen, watchText, 20, g.night.ink())
+
+	if g.gameOver {
+		drawCentered(screen, "GAME OVER", 60, g.night.ink())
+		msg := "Waiting for the next run..."
+		if s.lost {
+			msg = "The player stopped broadcasting"
+		}
+		drawCentered(screen, msg, 90, g.night.ink())
+	}
+}
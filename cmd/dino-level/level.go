@@ -0,0 +1,78 @@
+// Command dino-level lists the levels of the game and checks level files
+// without a display, so it runs anywhere a level is written, CI included.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/yongtenglei/dino/sim"
+	"github.com/yongtenglei/dino/storage"
+)
+
+const levelUsage = `usage: dino-level list
+       dino-level check [FILE...]`
+
+func main() {
+	if err := levelCommand(os.Args[1:], os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// levelCommand implements dino-level: list shows the levels, check plays
+// the given level files, or every level, headless and tells whether each
+// can be finished.
+func levelCommand(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(levelUsage)
+	}
+	dir, err := storage.LevelsDir()
+	if err != nil {
+		return err
+	}
+	switch args[0] {
+	case "list":
+		levels, errs := sim.LoadLevels(dir)
+		for _, l := range levels {
+			where := "yours"
+			if l.BuiltIn {
+				where = "built-in"
+			}
+			fmt.Fprintf(out, "%-24s %5.0fm  %-8s  %s\n", l.Script.Name, l.Script.Finish, where, l.File)
+		}
+		return errors.Join(errs...)
+	case "check":
+		files := args[1:]
+		var scripts []*sim.CourseScript
+		var errs []error
+		if len(files) == 0 {
+			var levels []*sim.Level
+			levels, errs = sim.LoadLevels(dir)
+			scripts = append(scripts, sim.Tutorial)
+			for _, l := range levels {
+				scripts = append(scripts, l.Script)
+			}
+		}
+		for _, file := range files {
+			c, err := sim.ReadCourse(file)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			scripts = append(scripts, c)
+		}
+		for _, c := range scripts {
+			inputs, ok := sim.SolveCourse(c)
+			if !ok {
+				errs = append(errs, fmt.Errorf("%s: found no way to the finish line", c.Name))
+				continue
+			}
+			fmt.Fprintf(out, "ok  %s: finished in %.1fs\n", c.Name, float64(len(inputs))/60)
+		}
+		return errors.Join(errs...)
+	}
+	return errors.New(levelUsage)
+}
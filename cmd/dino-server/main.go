@@ -0,0 +1,17 @@
+// Command dino-server runs the leaderboard the game submits its runs to.
+// It checks every run by playing its replay again, headless: it needs no
+// display server, nor even a graphics stack. `dino server` runs the same.
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/yongtenglei/dino/leaderboard"
+)
+
+func main() {
+	if err := leaderboard.Command("dino-server", os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
+}
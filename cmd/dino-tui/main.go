@@ -0,0 +1,27 @@
+// Command dino-tui plays the game in a terminal, drawing it with half-block
+// pixels and 24-bit colors. It builds on the simulation alone, without
+// ebiten, so it runs with no display server at all, over SSH included.
+package main
+
+import (
+	"flag"
+	_ "image/png"
+	"log"
+
+	"github.com/yongtenglei/dino/storage"
+)
+
+func main() {
+	profile := flag.String("profile", "", "play as this profile, created if new (remembered)")
+	flag.Parse()
+
+	// the profile is in use before anything is loaded from it
+	if *profile != "" {
+		if err := storage.PlayAs(*profile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := runTUI(); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,473 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"log"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/yongtenglei/dino/assets"
+	"github.com/yongtenglei/dino/sim"
+	"github.com/yongtenglei/dino/storage"
+)
+
+const (
+	// the terminal only shows the part of the world below tuiCropY, the sky
+	// above the highest clouds holds nothing
+	tuiCropY = 20
+
+	// terminals send no key release, so a key counts as held for a while
+	// after its last (auto-repeated) byte
+	tuiKeyHoldTicks = 40
+
+	tuiFrameTicks = 2 // redraw at 30 FPS
+)
+
+var (
+	tuiBackground = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	tuiText       = color.RGBA{0x53, 0x53, 0x53, 0xff}
+)
+
+type tuiKey int
+
+const (
+	tuiKeyNone tuiKey = iota
+	tuiKeyJump
+	tuiKeyDuck
+	tuiKeyShield
+	tuiKeyDaily
+	tuiKeyRestart
+	tuiKeyQuit
+)
+
+// tui plays the game in a terminal. It runs the same runner as the window,
+// draws the sprites as half-block pixels with 24-bit ANSI colors and reads
+// keys from raw stdin, so it needs no display server at all.
+type tui struct {
+	sheet image.Image
+	out   *bufio.Writer
+	keys  chan tuiKey
+
+	// pixel buffer, two pixels per terminal cell
+	pix   []color.RGBA
+	pw    int
+	ph    int
+	scale float64
+
+	jumpQueue   int
+	jumpHeld    bool
+	duckTicks   int
+	shieldPress bool
+	// restartTicks counts down while a restart key is held, a run only
+	// restarts on a fresh press after it
+	restartTicks int
+
+	player      *sim.Runner
+	startScreen bool
+	highScore   int
+	daily       bool
+	ranked      bool
+	runDay      string
+	history     *storage.History
+	wallet      *storage.Wallet
+	recorded    bool
+}
+
+// runTUI plays in the terminal on stdin until the player quits.
+func runTUI() error {
+	img, _, err := image.Decode(bytes.NewReader(assets.Sprite))
+	if err != nil {
+		return err
+	}
+
+	fd := int(os.Stdin.Fd()) // #nosec G115 -- file descriptors fit in an int
+	if !term.IsTerminal(fd) {
+		return errors.New("dino-tui needs a terminal on stdin")
+	}
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = term.Restore(fd, state) }()
+
+	t := &tui{
+		sheet:       img,
+		out:         bufio.NewWriterSize(os.Stdout, 1<<16),
+		keys:        make(chan tuiKey, 64),
+		startScreen: true,
+		history:     storage.LoadHistory(),
+		wallet:      storage.LoadWallet(),
+	}
+	// alternate screen, hidden cursor
+	fmt.Fprint(t.out, "\x1b[?1049h\x1b[?25l")
+	defer func() {
+		fmt.Fprint(t.out, "\x1b[0m\x1b[?25h\x1b[?1049l")
+		_ = t.out.Flush()
+	}()
+
+	go t.readKeys()
+
+	ticker := time.NewTicker(time.Second / 60)
+	defer ticker.Stop()
+	for tick := 0; ; tick++ {
+		<-ticker.C
+		if !t.update() {
+			return nil
+		}
+		if tick%tuiFrameTicks == 0 {
+			t.draw()
+		}
+	}
+}
+
+// readKeys turns raw stdin bytes into keys, arrow keys included.
+func (t *tui) readKeys() {
+	in := bufio.NewReader(os.Stdin)
+	for {
+		b, err := in.ReadByte()
+		if err != nil {
+			t.keys <- tuiKeyQuit
+			return
+		}
+		key := tuiKeyNone
+		switch b {
+		case ' ', 'k', 'K', 'w', 'W':
+			key = tuiKeyJump
+		case 'j', 'J', 's', 'S':
+			key = tuiKeyDuck
+		case 'x', 'X':
+			key = tuiKeyShield
+		case 'd', 'D':
+			key = tuiKeyDaily
+		case 'r', 'R', '\r':
+			key = tuiKeyRestart
+		case 'q', 'Q', 3: // 3 is Ctrl-C in raw mode
+			key = tuiKeyQuit
+		case 0x1b:
+			if next, err := in.ReadByte(); err == nil && next == '[' {
+				if arrow, err := in.ReadByte(); err == nil {
+					switch arrow {
+					case 'A':
+						key = tuiKeyJump
+					case 'B':
+						key = tuiKeyDuck
+					}
+				}
+			}
+		}
+		if key != tuiKeyNone {
+			t.keys <- key
+		}
+	}
+}
+
+func (t *tui) startRun(daily bool) {
+	t.daily = daily
+	t.ranked = false
+	seed := time.Now().UnixNano()
+	if daily {
+		now := time.Now()
+		t.runDay = sim.DayKey(now)
+		t.ranked = !t.history.HasRanked(t.runDay)
+		seed = sim.DailySeed(now)
+	}
+	// the ranked attempt of the day is used up as soon as it starts
+	if t.ranked {
+		t.history.StartRanked(t.runDay)
+		if err := t.history.Save(); err != nil {
+			log.Printf("saving score history: %v", err)
+		}
+	}
+	t.player = sim.NewRunner(seed)
+	t.startScreen = false
+	t.recorded = false
+	t.jumpQueue, t.jumpHeld, t.duckTicks, t.shieldPress = 0, false, 0, false
+}
+
+// update advances the game by one tick, it returns false to quit.
+func (t *tui) update() bool {
+	for drained := false; !drained; {
+		select {
+		case k := <-t.keys:
+			switch {
+			case k == tuiKeyQuit:
+				return false
+			case t.startScreen && (k == tuiKeyJump || k == tuiKeyRestart):
+				t.startRun(false)
+			case t.startScreen && k == tuiKeyDaily:
+				t.startRun(true)
+			case t.player != nil && t.player.Dead && (k == tuiKeyJump || k == tuiKeyRestart):
+				if t.restartTicks == 0 {
+					t.startRun(t.daily)
+				}
+			case k == tuiKeyJump:
+				t.jumpQueue++
+			case k == tuiKeyDuck:
+				t.duckTicks = tuiKeyHoldTicks
+			case k == tuiKeyShield:
+				t.shieldPress = true
+			}
+			if k == tuiKeyJump || k == tuiKeyRestart {
+				t.restartTicks = tuiKeyHoldTicks
+			}
+		default:
+			drained = true
+		}
+	}
+	if t.restartTicks > 0 {
+		t.restartTicks--
+	}
+
+	if t.startScreen {
+		return true
+	}
+
+	// every key press becomes one tick held and one released, so quick
+	// presses still register as separate jumps
+	if t.jumpHeld {
+		t.jumpHeld = false
+	} else if t.jumpQueue > 0 {
+		t.jumpQueue--
+		t.jumpHeld = true
+	}
+	in := sim.Input{Jump: t.jumpHeld, Duck: t.duckTicks > 0, Shield: t.shieldPress}
+	t.shieldPress = false
+	if t.duckTicks > 0 {
+		t.duckTicks--
+	}
+
+	t.player.Update(in)
+	t.highScore = max(t.highScore, t.player.Score)
+	if t.player.Dead && !t.recorded {
+		t.recorded = true
+		if t.daily {
+			t.history.Record(t.runDay, t.player.Score, t.ranked)
+			if err := t.history.Save(); err != nil {
+				log.Printf("saving score history: %v", err)
+			}
+		}
+		if t.player.CoinCount > 0 {
+			t.wallet.Deposit(t.player.CoinCount)
+			if err := t.wallet.Save(); err != nil {
+				log.Printf("saving wallet: %v", err)
+			}
+		}
+	}
+	return true
+}
+
+// resize fits the visible part of the world into the terminal.
+func (t *tui) resize() (cols, rows int) {
+	cols, rows, err := term.GetSize(int(os.Stdout.Fd())) // #nosec G115
+	if err != nil || cols < 20 || rows < 6 {
+		cols, rows = 80, 24
+	}
+	// the top row is the HUD
+	t.fit(cols, (rows-1)*2)
+	return cols, rows
+}
+
+// fit sizes the pixel buffer to pw by ph pixels and scales the visible part
+// of the world into it.
+func (t *tui) fit(pw, ph int) {
+	if pw != t.pw || ph != t.ph {
+		t.pw, t.ph = pw, ph
+		t.pix = make([]color.RGBA, pw*ph)
+	}
+	t.scale = max(float64(sim.ScreenWidth)/float64(pw), float64(sim.ScreenHeight-tuiCropY)/float64(ph))
+}
+
+// Sprite draws the sprite sheet region src with its top left corner at the
+// world position (x, y), sampling the nearest source pixel.
+func (t *tui) Sprite(src image.Rectangle, x, y float64) {
+	x0 := int((x) / t.scale)
+	y0 := int((y - tuiCropY) / t.scale)
+	w := int(float64(src.Dx()) / t.scale)
+	h := int(float64(src.Dy()) / t.scale)
+	for py := max(y0, 0); py < min(y0+h, t.ph); py++ {
+		sy := src.Min.Y + int(float64(py-y0)*t.scale)
+		for px := max(x0, 0); px < min(x0+w, t.pw); px++ {
+			sx := src.Min.X + int(float64(px-x0)*t.scale)
+			r, g, b, a := t.sheet.At(sx, sy).RGBA()
+			if a < 0x8000 {
+				continue
+			}
+			t.pix[py*t.pw+px] = color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), 0xff}
+		}
+	}
+}
+
+// Disc paints the pixels whose center is within r of the world position
+// (x, y). Discs much smaller than a pixel are details, like the hole of a
+// coin, left out not to blot out what they are on.
+func (t *tui) Disc(x, y, r float64, clr color.Color) {
+	if r < t.scale/2 {
+		return
+	}
+	t.paint(x, y, r, func(d float64) bool { return d <= r }, clr)
+}
+
+// Ring paints the pixels within width of the circle of radius r around the
+// world position (x, y).
+func (t *tui) Ring(x, y, r, width float64, clr color.Color) {
+	t.paint(x, y, r+width/2, func(d float64) bool { return math.Abs(d-r) <= max(width/2, t.scale/2) }, clr)
+}
+
+// Glyph draws nothing, text is too small to read at the pixel size of a
+// terminal.
+func (t *tui) Glyph(string, float64, float64, color.Color) {}
+
+// paint sets the pixels around the world position (x, y), up to reach away,
+// whose distance d from it is in.
+func (t *tui) paint(x, y, reach float64, in func(d float64) bool, clr color.Color) {
+	r, g, b, _ := clr.RGBA()
+	c := color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), 0xff}
+	x0, x1 := int((x-reach)/t.scale), int((x+reach)/t.scale)
+	y0, y1 := int((y-reach-tuiCropY)/t.scale), int((y+reach-tuiCropY)/t.scale)
+	for py := max(y0, 0); py <= min(y1, t.ph-1); py++ {
+		for px := max(x0, 0); px <= min(x1, t.pw-1); px++ {
+			wx := (float64(px) + 0.5) * t.scale
+			wy := (float64(py)+0.5)*t.scale + tuiCropY
+			if in(math.Hypot(wx-x, wy-y)) {
+				t.pix[py*t.pw+px] = c
+			}
+		}
+	}
+}
+
+func (t *tui) draw() {
+	cols, rows := t.resize()
+	for i := range t.pix {
+		t.pix[i] = tuiBackground
+	}
+
+	out := t.out
+	fmt.Fprint(out, "\x1b[H")
+
+	if t.startScreen {
+		t.drawStart(cols, rows)
+		_ = out.Flush()
+		return
+	}
+
+	p := t.player
+	t.drawWorld(p)
+
+	hud := fmt.Sprintf(" Score: %d  Coins: %d  High Score: %d", p.Score, p.CoinCount, t.highScore)
+	for _, powerText := range p.PowerHUD() {
+		hud += "  " + powerText
+	}
+	if p.IsDucking {
+		hud += fmt.Sprintf("  Duck timeout: %.1fs", max(3.0-p.DuckDuration, 0))
+	}
+	if t.daily {
+		hud += fmt.Sprintf("  DAILY %s", t.runDay)
+		if !t.ranked {
+			hud += " (practice)"
+		}
+	}
+	if p.Dead {
+		hud += "  GAME OVER - SPACE/R to restart, Q to quit"
+		if t.daily {
+			best, _ := t.history.BestOf(t.runDay)
+			hud += fmt.Sprintf("  Today's best: %d, streak: %d", best, t.history.Streak(t.runDay))
+		}
+	}
+	t.writeLine(hud, cols)
+	t.flushPixels(rows - 1)
+	_ = out.Flush()
+}
+
+// drawWorld paints the clouds, ground, dino and everything on the course of
+// p into the pixel buffer.
+func (t *tui) drawWorld(p *sim.Runner) {
+	p.Clouds.Draw(t)
+	groundY := float64(sim.ScreenHeight - sim.GroundHeight - 18)
+	for i := 0; i < 2; i++ {
+		offsetX := -p.GroundX + float64(sim.GroundRect.Dx()*i)
+		if i == 1 {
+			offsetX -= 5 // fix the little gap
+		}
+		t.Sprite(sim.GroundRect, offsetX, groundY)
+	}
+
+	switch {
+	case p.Dead:
+		t.Sprite(sim.DinoDeadRects[p.AnimFrame%len(sim.DinoDeadRects)], p.PlayerX, p.PlayerY)
+	case p.IsDucking:
+		t.Sprite(sim.DinoDuckRects[p.AnimFrame%len(sim.DinoDuckRects)], p.PlayerX, p.PlayerY+sim.DuckYOffset)
+	case !p.OnGround && p.VY < 0:
+		t.Sprite(sim.DinoStandRects[p.AnimFrame%len(sim.DinoStandRects)], p.PlayerX, p.PlayerY)
+	default:
+		t.Sprite(sim.DinoRunningRects[p.AnimFrame%len(sim.DinoRunningRects)], p.PlayerX, p.PlayerY)
+	}
+	for _, e := range p.Entities {
+		e.Draw(t)
+	}
+}
+
+func (t *tui) drawStart(cols, rows int) {
+	lines := append([]string{}, assets.Logo...)
+	lines = append(lines,
+		"Press SPACE to Start",
+		"D: Daily Challenge | Q: Quit",
+		"",
+		"SPACE/K/UP: Jump | DOWN/J: Duck | X: Shield",
+	)
+	top := max((rows-len(lines))/2, 0)
+	for row := 0; row < rows; row++ {
+		line := ""
+		if i := row - top; i >= 0 && i < len(lines) {
+			line = strings.Repeat(" ", max((cols-len(lines[i]))/2, 0)) + lines[i]
+		}
+		t.writeLine(line, cols)
+		if row < rows-1 {
+			fmt.Fprint(t.out, "\r\n")
+		}
+	}
+}
+
+// writeLine writes s as a full-width row of text.
+func (t *tui) writeLine(s string, cols int) {
+	if len(s) > cols {
+		s = s[:cols]
+	}
+	fmt.Fprintf(t.out, "\x1b[38;2;%d;%d;%dm\x1b[48;2;%d;%d;%dm%s%s\x1b[0m",
+		tuiText.R, tuiText.G, tuiText.B,
+		tuiBackground.R, tuiBackground.G, tuiBackground.B,
+		s, strings.Repeat(" ", cols-len(s)))
+}
+
+// flushPixels writes the pixel buffer as rows of upper half blocks, the
+// foreground color being the top pixel and the background the bottom one.
+func (t *tui) flushPixels(rows int) {
+	var fg, bg color.RGBA
+	first := true
+	for row := 0; row < rows && 2*row+1 < t.ph; row++ {
+		fmt.Fprint(t.out, "\r\n")
+		for x := 0; x < t.pw; x++ {
+			top := t.pix[2*row*t.pw+x]
+			bottom := t.pix[(2*row+1)*t.pw+x]
+			if first || top != fg {
+				fmt.Fprintf(t.out, "\x1b[38;2;%d;%d;%dm", top.R, top.G, top.B)
+				fg = top
+			}
+			if first || bottom != bg {
+				fmt.Fprintf(t.out, "\x1b[48;2;%d;%d;%dm", bottom.R, bottom.G, bottom.B)
+				bg = bottom
+			}
+			first = false
+			fmt.Fprint(t.out, "▀")
+		}
+	}
+	fmt.Fprint(t.out, "\x1b[0m")
+}
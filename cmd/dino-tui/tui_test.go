@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"testing"
+
+	"github.com/yongtenglei/dino/assets"
+	"github.com/yongtenglei/dino/sim"
+)
+
+func TestCloudsShowUp(t *testing.T) {
+	sheet, _, err := image.Decode(bytes.NewReader(assets.Sprite))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu := &tui{sheet: sheet}
+	tu.fit(80, 46)
+	for i := range tu.pix {
+		tu.pix[i] = tuiBackground
+	}
+
+	p := sim.NewRunner(1)
+	p.Entities = nil
+	tu.drawWorld(p)
+
+	seen := 0
+	for _, c := range p.Clouds.Items {
+		if c.X < 0 || c.X+c.W > sim.ScreenWidth {
+			continue
+		}
+		seen++
+		if !tu.painted(c.X, c.Y, c.W, c.H) {
+			t.Errorf("cloud at (%.0f, %.0f) is not in the frame", c.X, c.Y)
+		}
+	}
+	if seen == 0 {
+		t.Fatal("no cloud fully on screen to look for")
+	}
+}
+
+// painted reports whether any pixel over the world box at (x, y) of w by h
+// was drawn on.
+func (t *tui) painted(x, y, w, h float64) bool {
+	for py := int((y - tuiCropY) / t.scale); py <= int((y+h-tuiCropY)/t.scale); py++ {
+		for px := int(x / t.scale); px <= int((x+w)/t.scale); px++ {
+			if py >= 0 && py < t.ph && px >= 0 && px < t.pw && t.pix[py*t.pw+px] != tuiBackground {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/yongtenglei/dino/sim"
+)
+
+// The game always plays on a screenWidth x screenHeight canvas, whatever the
+// window looks like. Showing more of the course on wide screens would let
+// players see obstacles coming sooner, which is not fair on the leaderboard,
+// so wide windows get borders instead.
+
+const (
+	minWindowWidth  = sim.ScreenWidth / 4
+	minWindowHeight = sim.ScreenHeight / 4
+)
+
+// letterbox fills the borders around the game when the window does not have
+// its aspect ratio.
+var letterbox = color.RGBA{0x20, 0x20, 0x20, 0xff}
+
+// updateDisplay toggles fullscreen on F11 or Alt+Enter and keeps track of
+// the window size, so both can be restored on the next launch.
+func (g *Game) updateDisplay() {
+	pressed := ebiten.IsKeyPressed(ebiten.KeyF11) ||
+		(ebiten.IsKeyPressed(ebiten.KeyAlt) && ebiten.IsKeyPressed(ebiten.KeyEnter))
+	if pressed && !g.lastFullscreenKeyPressed {
+		g.settings.Fullscreen = !ebiten.IsFullscreen()
+		ebiten.SetFullscreen(g.settings.Fullscreen)
+	}
+	g.lastFullscreenKeyPressed = pressed
+
+	if !ebiten.IsFullscreen() {
+		g.settings.WindowWidth, g.settings.WindowHeight = ebiten.WindowSize()
+	}
+}
+
+// present scales the canvas up to the screen, centered and keeping its
+// aspect ratio.
+func (g *Game) present(screen *ebiten.Image) {
+	screen.Fill(letterbox)
+
+	sw, sh := float64(screen.Bounds().Dx()), float64(screen.Bounds().Dy())
+	scale := min(sw/sim.ScreenWidth, sh/sim.ScreenHeight)
+	if g.settings.IntegerScale && scale >= 1 {
+		scale = math.Floor(scale)
+	}
+
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(scale, scale)
+	dx, dy := g.fx.shakeOffset()
+	op.GeoM.Translate(math.Floor((sw-sim.ScreenWidth*scale)/2+dx*scale), math.Floor((sh-sim.ScreenHeight*scale)/2+dy*scale))
+	// whole factors keep the pixels sharp, anything else is smoothed
+	if scale != math.Floor(scale) {
+		op.Filter = ebiten.FilterLinear
+	}
+	screen.DrawImage(g.canvas, op)
+}
+
+// Layout lays the screen out in device pixels rather than the usual
+// device-independent ones, so on HiDPI displays the canvas and its bitmap
+// text are scaled once, straight to the physical resolution.
+func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
+	s := ebiten.Monitor().DeviceScaleFactor()
+	return int(float64(outsideWidth) * s), int(float64(outsideHeight) * s)
+}
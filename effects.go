@@ -0,0 +1,148 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/yongtenglei/dino/sim"
+)
+
+const (
+	dustParticles  = 8
+	burstParticles = 24
+	coinParticles  = 4
+	particleLife   = 30
+	particleSize   = 4
+
+	// deathShake is the screen shake amplitude in pixels on death.
+	deathShake      = 12
+	shakeDecay      = 0.85
+	shieldHitStop   = 6
+	particleGravity = 0.15
+)
+
+type particle struct {
+	owner  *sim.Runner
+	x, y   float64
+	vx, vy float64
+	life   int
+	size   float64
+}
+
+// effects is the juice on top of the runs: particles, screen shake and
+// hit-stop. It is cosmetic, it only reads what runners did and never
+// touches the course RNG.
+type effects struct {
+	cfg       *settings
+	particles []particle
+	shake     float64
+	hitStop   int
+}
+
+func newEffects(cfg *settings) *effects {
+	return &effects{cfg: cfg}
+}
+
+// intensity scales the effect of setting, down to nothing with reduced
+// motion on.
+func (fx *effects) intensity(setting float64) float64 {
+	if fx.cfg.ReducedMotion {
+		return 0
+	}
+	return setting
+}
+
+// onEvent is the subscriber starting the effects of p's events.
+func (fx *effects) onEvent(p *sim.Runner, e sim.Event) {
+	parts := fx.intensity(fx.cfg.Particles)
+	x, y, w, h := p.DinoBox()
+	switch e.Kind {
+	case sim.EventLanded, sim.EventDoubleJumped:
+		// dust kicked up at the dino's feet
+		fx.spawn(p, x+w/2, y+h, int(dustParticles*parts), 1.5, -math.Pi, 0)
+	case sim.EventCoin:
+		fx.spawn(p, x+w, y+h/3, int(coinParticles*parts), 2, 0, 2*math.Pi)
+	case sim.EventSmashed:
+		fx.spawn(p, x+w, y+h/2, int(burstParticles*parts/2), 4, -math.Pi/2, math.Pi/2)
+	case sim.EventShieldBroken:
+		fx.spawn(p, x+w, y+h/2, int(burstParticles*parts), 4, 0, 2*math.Pi)
+		fx.hitStop = int(math.Round(shieldHitStop * fx.intensity(fx.cfg.HitStop)))
+	case sim.EventDied:
+		fx.shake = deathShake * fx.intensity(fx.cfg.Shake)
+	}
+}
+
+// spawn adds n particles at (x, y) flying out at up to speed, in directions
+// between angles from and to.
+func (fx *effects) spawn(owner *sim.Runner, x, y float64, n int, speed, from, to float64) {
+	for range n {
+		a := from + rand.Float64()*(to-from)    // #nosec G404
+		v := speed * (0.3 + 0.7*rand.Float64()) // #nosec G404
+		fx.particles = append(fx.particles, particle{
+			owner: owner,
+			x:     x,
+			y:     y,
+			vx:    math.Cos(a) * v,
+			vy:    math.Sin(a) * v,
+			life:  particleLife,
+			size:  particleSize * (0.5 + rand.Float64()/2), // #nosec G404
+		})
+	}
+}
+
+// update moves the particles along and lets the shake settle.
+func (fx *effects) update() {
+	alive := fx.particles[:0]
+	for _, pt := range fx.particles {
+		pt.life--
+		if pt.life <= 0 {
+			continue
+		}
+		pt.x += pt.vx
+		pt.y += pt.vy
+		pt.vy += particleGravity
+		alive = append(alive, pt)
+	}
+	fx.particles = alive
+
+	fx.shake *= shakeDecay
+	if fx.shake < 0.5 {
+		fx.shake = 0
+	}
+}
+
+// frozen reports whether the game is in a hit-stop, and counts it down.
+func (fx *effects) frozen() bool {
+	if fx.hitStop > 0 {
+		fx.hitStop--
+		return true
+	}
+	return false
+}
+
+// shakeOffset is how far the frame is thrown off this tick.
+func (fx *effects) shakeOffset() (float64, float64) {
+	if fx.shake == 0 {
+		return 0, 0
+	}
+	return (rand.Float64()*2 - 1) * fx.shake, (rand.Float64()*2 - 1) * fx.shake // #nosec G404
+}
+
+// draw draws the particles thrown by p, fading out as they die.
+func (fx *effects) draw(dst *ebiten.Image, p *sim.Runner) {
+	for _, pt := range fx.particles {
+		if pt.owner != p {
+			continue
+		}
+		clr := sim.SpriteGray
+		a := float64(pt.life) / particleLife
+		clr.R = uint8(float64(clr.R) * a)
+		clr.G = uint8(float64(clr.G) * a)
+		clr.B = uint8(float64(clr.B) * a)
+		clr.A = uint8(float64(clr.A) * a)
+		vector.DrawFilledRect(dst, float32(pt.x), float32(pt.y), float32(pt.size), float32(pt.size), clr, false)
+	}
+}
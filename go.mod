@@ -5,6 +5,7 @@ go 1.24.3
 require (
 	github.com/hajimehoshi/ebiten/v2 v2.8.8
 	golang.org/x/image v0.20.0
+	golang.org/x/term v0.24.0
 )
 
 require (
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	defaultLANAddr = ":7777"
+
+	lanOutgoingBuffer = 4096
+	lanIncomingBuffer = 4096
+
+	// lanTick is one tick of the session clock, a frame of the game
+	lanTick = time.Second / 60
+)
+
+// Message types of the LAN protocol. Every message is one JSON object per
+// line. Clients only ever talk to the host, which relays what they send to
+// every other client.
+const (
+	msgHello   = "hello"   // client -> host: Name
+	msgWelcome = "welcome" // host -> client: ID, Names, Tick of the session clock
+	msgJoin    = "join"    // a player entered the lobby: ID, Name
+	msgLeave   = "leave"   // a player disconnected: ID
+	msgStart   = "start"   // host starts a race: Seed, Tick of the session clock it starts on
+	msgInput   = "input"   // a player's controls for one tick: ID, Tick, Jump, Duck, Shield
+	msgDied    = "died"    // a player's dino died: ID, Tick, Score
+)
+
+type netMessage struct {
+	Type   string         `json:"type"`
+	ID     int            `json:"id"`
+	Name   string         `json:"name,omitempty"`
+	Names  map[int]string `json:"names,omitempty"`
+	Seed   int64          `json:"seed,omitempty"`
+	Tick   int            `json:"tick,omitempty"`
+	Jump   bool           `json:"jump,omitempty"`
+	Duck   bool           `json:"duck,omitempty"`
+	Shield bool           `json:"shield,omitempty"`
+	Score  int            `json:"score,omitempty"`
+}
+
+// lanPeer is one end of a connection. Writes go through a buffered queue so
+// a slow peer never stalls the game loop. Anything JSON encodable can be
+// sent, spectator streams reuse it for their own messages.
+type lanPeer struct {
+	id   int
+	conn net.Conn
+	out  chan any
+	once sync.Once
+}
+
+func newLANPeer(id int, conn net.Conn) *lanPeer {
+	p := &lanPeer{id: id, conn: conn, out: make(chan any, lanOutgoingBuffer)}
+	go func() {
+		w := bufio.NewWriter(conn)
+		enc := json.NewEncoder(w)
+		for m := range p.out {
+			if err := enc.Encode(m); err != nil {
+				p.close()
+				return
+			}
+			if len(p.out) == 0 {
+				if err := w.Flush(); err != nil {
+					p.close()
+					return
+				}
+			}
+		}
+	}()
+	return p
+}
+
+func (p *lanPeer) send(m any) {
+	select {
+	case p.out <- m:
+	default:
+		// the peer cannot keep up, drop it rather than desync silently
+		p.close()
+	}
+}
+
+func (p *lanPeer) close() {
+	p.once.Do(func() { _ = p.conn.Close() })
+}
+
+// lanSession is this instance's view of a LAN race, either as the host or
+// as a client. Everything other players do arrives on incoming.
+type lanSession struct {
+	id       int
+	name     string
+	host     bool
+	incoming chan netMessage
+	// epoch is when the session clock of the host was at tick 0, as seen
+	// from here
+	epoch time.Time
+
+	// host side
+	ln     net.Listener
+	mu     sync.Mutex
+	peers  map[int]*lanPeer
+	names  map[int]string
+	nextID int
+
+	// client side
+	server *lanPeer
+}
+
+// hostLAN starts listening for players on addr. The host itself plays as
+// player 0.
+func hostLAN(addr, name string) (*lanSession, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &lanSession{
+		id:       0,
+		name:     name,
+		host:     true,
+		incoming: make(chan netMessage, lanIncomingBuffer),
+		epoch:    time.Now(),
+		ln:       ln,
+		peers:    map[int]*lanPeer{},
+		names:    map[int]string{0: name},
+		nextID:   1,
+	}
+	go s.accept()
+	return s, nil
+}
+
+func (s *lanSession) accept() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.serve(conn)
+	}
+}
+
+// serve handles one client: registers it on hello, then relays everything
+// it sends to the other players.
+func (s *lanSession) serve(conn net.Conn) {
+	dec := json.NewDecoder(bufio.NewReader(conn))
+	var hello netMessage
+	if err := dec.Decode(&hello); err != nil || hello.Type != msgHello {
+		_ = conn.Close()
+		return
+	}
+
+	s.mu.Lock()
+	id := s.nextID
+	s.nextID++
+	peer := newLANPeer(id, conn)
+	s.peers[id] = peer
+	s.names[id] = hello.Name
+	names := make(map[int]string, len(s.names))
+	for k, v := range s.names {
+		names[k] = v
+	}
+	s.mu.Unlock()
+
+	peer.send(netMessage{Type: msgWelcome, ID: id, Names: names, Tick: s.tick()})
+	s.relay(netMessage{Type: msgJoin, ID: id, Name: hello.Name}, id)
+
+	for {
+		var m netMessage
+		if err := dec.Decode(&m); err != nil {
+			break
+		}
+		// a client may only speak for itself
+		m.ID = id
+		s.relay(m, id)
+	}
+
+	s.mu.Lock()
+	delete(s.peers, id)
+	delete(s.names, id)
+	s.mu.Unlock()
+	peer.close()
+	s.relay(netMessage{Type: msgLeave, ID: id}, id)
+}
+
+// relay delivers m to the host game and every client but the sender.
+func (s *lanSession) relay(m netMessage, from int) {
+	s.mu.Lock()
+	for id, p := range s.peers {
+		if id != from {
+			p.send(m)
+		}
+	}
+	s.mu.Unlock()
+	s.incoming <- m
+}
+
+// joinLAN connects to the host at addr and waits to be welcomed.
+func joinLAN(addr, name string) (*lanSession, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bufio.NewReader(conn))
+	sent := time.Now()
+	if err := json.NewEncoder(conn).Encode(netMessage{Type: msgHello, Name: name}); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	var welcome netMessage
+	if err := dec.Decode(&welcome); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	if welcome.Type != msgWelcome {
+		_ = conn.Close()
+		return nil, fmt.Errorf("unexpected %q from host", welcome.Type)
+	}
+	// the welcome took about half the round trip to get here, the host's
+	// clock went on meanwhile
+	now := time.Now()
+	behind := time.Duration(welcome.Tick)*lanTick + now.Sub(sent)/2
+
+	s := &lanSession{
+		id:       welcome.ID,
+		name:     name,
+		incoming: make(chan netMessage, lanIncomingBuffer),
+		epoch:    now.Add(-behind),
+		server:   newLANPeer(0, conn),
+	}
+	// the roster is replayed as joins so the game sees a single kind of event
+	for id, n := range welcome.Names {
+		if id != s.id {
+			s.incoming <- netMessage{Type: msgJoin, ID: id, Name: n}
+		}
+	}
+	go func() {
+		for {
+			var m netMessage
+			if err := dec.Decode(&m); err != nil {
+				break
+			}
+			s.incoming <- m
+		}
+		s.server.close()
+		s.incoming <- netMessage{Type: msgLeave, ID: 0}
+	}()
+	return s, nil
+}
+
+// tick is the session clock: the ticks since the host opened the session,
+// the same on every instance give or take the latency estimate.
+func (s *lanSession) tick() int {
+	return int(time.Since(s.epoch) / lanTick)
+}
+
+// send tells every other player about m, on behalf of this instance.
+func (s *lanSession) send(m netMessage) {
+	m.ID = s.id
+	if !s.host {
+		s.server.send(m)
+		return
+	}
+	s.mu.Lock()
+	for _, p := range s.peers {
+		p.send(m)
+	}
+	s.mu.Unlock()
+}
+
+func (s *lanSession) close() {
+	if s.host {
+		_ = s.ln.Close()
+		s.mu.Lock()
+		for _, p := range s.peers {
+			p.close()
+		}
+		s.mu.Unlock()
+		return
+	}
+	s.server.close()
+}
@@ -0,0 +1,276 @@
+// Package leaderboard is the leaderboard server and its client. The server
+// trusts nothing but replays: it plays every submitted run again and only
+// keeps the scores that reproduce. It builds on the simulation alone, so it
+// runs on machines with no display.
+package leaderboard
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yongtenglei/dino/sim"
+)
+
+const (
+	// BoardEndless is the board of endless runs. The board of the daily
+	// course of a day is DailyBoardPrefix followed by the day.
+	BoardEndless     = "endless"
+	DailyBoardPrefix = "daily-"
+
+	maxBoardEntries   = 100
+	maxPlayerNameLen  = 32
+	maxSubmissionSize = 4 << 20
+)
+
+type boardEntry struct {
+	Name  string    `json:"name"`
+	Score int       `json:"score"`
+	Seed  int64     `json:"seed"`
+	At    time.Time `json:"at"`
+}
+
+// Submission is what a client posts after a run. Only the replay is trusted:
+// the server plays it again and keeps the score it actually reaches.
+type Submission struct {
+	Name   string     `json:"name"`
+	Board  string     `json:"board"`
+	Replay sim.Replay `json:"replay"`
+}
+
+// Result is the answer of the server to a submission: the rank the run got
+// on its board, 0 when it did not make it.
+type Result struct {
+	Rank  int `json:"rank"`
+	Score int `json:"score"`
+}
+
+// leaderboard is the server state, saved as a single JSON file.
+type leaderboard struct {
+	path   string
+	mu     sync.Mutex
+	Boards map[string][]boardEntry `json:"boards"`
+}
+
+func loadLeaderboard(path string) (*leaderboard, error) {
+	lb := &leaderboard{path: path, Boards: map[string][]boardEntry{}}
+	data, err := os.ReadFile(path) // #nosec G304 -- path given by the operator
+	if errors.Is(err, fs.ErrNotExist) {
+		return lb, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, lb); err != nil {
+		return nil, err
+	}
+	if lb.Boards == nil {
+		lb.Boards = map[string][]boardEntry{}
+	}
+	return lb, nil
+}
+
+func (lb *leaderboard) save() error {
+	data, err := json.MarshalIndent(lb, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := lb.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, lb.path)
+}
+
+// checkBoard rejects boards the replay does not belong to. Daily boards only
+// take runs on the course of their day.
+func checkBoard(board string, seed int64) error {
+	if board == BoardEndless {
+		return nil
+	}
+	day, ok := strings.CutPrefix(board, DailyBoardPrefix)
+	if !ok {
+		return fmt.Errorf("unknown board %q", board)
+	}
+	t, err := time.ParseInLocation(sim.DayLayout, day, time.Local)
+	if err != nil {
+		return fmt.Errorf("unknown board %q", board)
+	}
+	if sim.DailySeed(t) != seed {
+		return errors.New("replay is not on the daily course")
+	}
+	return nil
+}
+
+// add inserts a verified entry and returns its 1-based rank, or 0 when it
+// did not make the board.
+func (lb *leaderboard) add(board string, e boardEntry) (int, error) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	entries := append(lb.Boards[board], e)
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Score > entries[j].Score })
+	rank := 0
+	for i := range entries {
+		if entries[i] == e {
+			rank = i + 1
+			break
+		}
+	}
+	if len(entries) > maxBoardEntries {
+		entries = entries[:maxBoardEntries]
+	}
+	if rank > maxBoardEntries {
+		rank = 0
+	}
+	lb.Boards[board] = entries
+	return rank, lb.save()
+}
+
+func (lb *leaderboard) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var sub Submission
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSubmissionSize)).Decode(&sub); err != nil {
+		http.Error(w, "bad submission: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	sub.Name = strings.TrimSpace(sub.Name)
+	if sub.Name == "" || len(sub.Name) > maxPlayerNameLen {
+		http.Error(w, "bad player name", http.StatusBadRequest)
+		return
+	}
+	if err := checkBoard(sub.Board, sub.Replay.Seed); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if sub.Replay.Shield != nil {
+		http.Error(w, "runs by custom shield rules are not ranked", http.StatusBadRequest)
+		return
+	}
+	if err := sub.Replay.Verify(); err != nil {
+		log.Printf("rejected run of %s: %v", sub.Name, err)
+		http.Error(w, "replay rejected: "+err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	e := boardEntry{Name: sub.Name, Score: sub.Replay.Score, Seed: sub.Replay.Seed, At: time.Now().UTC()}
+	rank, err := lb.add(sub.Board, e)
+	if err != nil {
+		log.Printf("saving leaderboard: %v", err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(Result{Rank: rank, Score: e.Score})
+}
+
+func (lb *leaderboard) handleScores(w http.ResponseWriter, r *http.Request) {
+	board := r.URL.Query().Get("board")
+	if board == "" {
+		board = BoardEndless
+	}
+	lb.mu.Lock()
+	entries := append([]boardEntry(nil), lb.Boards[board]...)
+	lb.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(entries)
+}
+
+var leaderboardPage = template.Must(template.New("board").Funcs(template.FuncMap{
+	"rank": func(i int) int { return i + 1 },
+}).Parse(`<!doctype html>
+<html><head><meta charset="utf-8"><title>Dino leaderboard</title>
+<style>body{font-family:monospace;margin:2em}td,th{padding:0 1em;text-align:left}</style>
+</head><body><h1>Dino leaderboard</h1>
+{{range $board, $entries := .}}<h2>{{$board}}</h2>
+<table><tr><th>#</th><th>Name</th><th>Score</th><th>Date</th></tr>
+{{range $i, $e := $entries}}<tr><td>{{rank $i}}</td><td>{{$e.Name}}</td><td>{{$e.Score}}</td><td>{{$e.At.Format "2006-01-02 15:04"}}</td></tr>
+{{end}}</table>
+{{else}}<p>No runs yet.</p>{{end}}
+</body></html>
+`))
+
+func (lb *leaderboard) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	lb.mu.Lock()
+	boards := make(map[string][]boardEntry, len(lb.Boards))
+	for k, v := range lb.Boards {
+		boards[k] = append([]boardEntry(nil), v...)
+	}
+	lb.mu.Unlock()
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := leaderboardPage.Execute(w, boards); err != nil {
+		log.Printf("rendering leaderboard: %v", err)
+	}
+}
+
+// Command runs the server with the command line args, as `dino server` and
+// dino-server do. name is the command, for the usage message.
+func Command(name string, args []string) error {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	addr := fs.String("addr", ":8080", "address to serve the leaderboard on")
+	db := fs.String("db", "leaderboard.json", "file the leaderboard is stored in")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	return Serve(*addr, *db)
+}
+
+// Serve loads the leaderboard stored in db, or starts an empty one, and
+// serves it on addr: the page of the boards, their scores as JSON and the
+// submissions of runs.
+func Serve(addr, db string) error {
+	lb, err := loadLeaderboard(db)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", lb.handleIndex)
+	mux.HandleFunc("/api/scores", lb.handleScores)
+	mux.HandleFunc("/api/submit", lb.handleSubmit)
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	log.Printf("leaderboard listening on %s", addr)
+	return srv.ListenAndServe()
+}
+
+// Submit posts a finished run to the leaderboard server at baseURL.
+func Submit(baseURL string, sub Submission) (Result, error) {
+	var res Result
+	body, err := json.Marshal(sub)
+	if err != nil {
+		return res, err
+	}
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Post(strings.TrimRight(baseURL, "/")+"/api/submit", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return res, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return res, errors.New(strings.TrimSpace(string(msg)))
+	}
+	err = json.NewDecoder(resp.Body).Decode(&res)
+	return res, err
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+
+	"github.com/yongtenglei/dino/sim"
+	"github.com/yongtenglei/dino/storage"
+)
+
+// levelScreen is the state of the level select screen.
+type levelScreen struct {
+	levels []*sim.Level
+	// errs are the levels that could not be read
+	errs   []error
+	cursor int
+	dir    string
+}
+
+// openLevels loads the levels, picking up those added since last time, and
+// shows them.
+func (g *Game) openLevels() {
+	ls := &g.levels
+	ls.dir, _ = storage.LevelsDir()
+	ls.levels, ls.errs = sim.LoadLevels(ls.dir)
+	for _, err := range ls.errs {
+		log.Printf("loading levels: %v", err)
+	}
+	ls.cursor = min(ls.cursor, max(len(ls.levels)-1, 0))
+	g.menu = menuLevels
+}
+
+// updateLevels runs the level select screen: UP/DOWN pick a level, ENTER
+// plays it and ESC goes back to the start screen.
+func (g *Game) updateLevels() {
+	ls := &g.levels
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyEscape):
+		g.menu = menuNone
+	case len(ls.levels) == 0:
+	case inpututil.IsKeyJustPressed(ebiten.KeyUp):
+		ls.cursor = (ls.cursor + len(ls.levels) - 1) % len(ls.levels)
+	case inpututil.IsKeyJustPressed(ebiten.KeyDown):
+		ls.cursor = (ls.cursor + 1) % len(ls.levels)
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnter):
+		g.menu = menuNone
+		g.startCourse(ls.levels[ls.cursor].Script)
+	}
+}
+
+func (g *Game) drawLevels(screen *ebiten.Image) {
+	screen.Fill(color.RGBA{0x30, 0x30, 0x40, 0xff})
+
+	ls := &g.levels
+	drawCentered(screen, "LEVELS", 80, color.White)
+	for i, l := range ls.levels {
+		name := l.Script.Name
+		if !l.BuiltIn {
+			name += " *"
+		}
+		best := "not finished yet"
+		if rec := g.courses[l.Script.Name]; rec != nil && rec.Completed > 0 {
+			best = fmt.Sprintf("best %5.1fs %6d pts", float64(rec.BestTicks)/60, rec.BestScore)
+		}
+		line := fmt.Sprintf("%-24s %4.0fm  %-22s", name, l.Script.Finish, best)
+		if i == ls.cursor {
+			line = "> " + line + " <"
+		}
+		drawCentered(screen, line, float64(120+i*20), color.White)
+	}
+
+	y := float64(140 + len(ls.levels)*20)
+	if len(ls.errs) > 0 {
+		drawCentered(screen, fmt.Sprintf("%d level(s) could not be read, `dino-level check` tells why", len(ls.errs)), y, color.White)
+	}
+	drawCentered(screen, "UP/DOWN: Pick | ENTER: Play | ESC: Back", y+40, gray)
+	drawCentered(screen, "Your own levels (*) go in "+ls.dir, y+60, gray)
+}
@@ -2,590 +2,522 @@ package main
 
 import (
 	"bytes"
-	_ "embed"
+	"errors"
+	"flag"
 	"fmt"
 	"image"
 	"image/color"
 	_ "image/png"
-	"math"
-	"math/rand"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/audio"
-	"github.com/hajimehoshi/ebiten/v2/audio/wav"
+	"github.com/hajimehoshi/ebiten/v2/colorm"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	text "github.com/hajimehoshi/ebiten/v2/text/v2"
 	"golang.org/x/image/font/basicfont"
+
+	"github.com/yongtenglei/dino/assets"
+	"github.com/yongtenglei/dino/leaderboard"
+	"github.com/yongtenglei/dino/sim"
+	"github.com/yongtenglei/dino/storage"
 )
 
-//go:embed assets/sprite.png
-var spriteSheet []byte
+var gray = color.RGBA{0x88, 0x88, 0x88, 0xff}
 
-//go:embed assets/jump.wav
-var jumpWav []byte
+var face = text.NewGoXFace(basicfont.Face7x13)
 
-//go:embed assets/die.wav
-var dieWav []byte
+type gameMode int
 
-//go:embed assets/point.wav
-var pointWav []byte
+const (
+	modeEndless gameMode = iota
+	modeDaily
+	modeVersus
+	modeLAN
+	modeWatch
+	// modeReplay plays the replay of the run just over
+	modeReplay
+	// modeCourse runs a scripted course, like the tutorial
+	modeCourse
+)
 
-//go:embed assets/run.wav
-var runWav []byte
+// menuScreen is the menu open over the start screen, if any.
+type menuScreen int
 
-//go:embed assets/shield.wav
-var shieldWav []byte
+const (
+	menuNone menuScreen = iota
+	menuSkins
+	menuOptions
+	menuAchievements
+	menuProfiles
+	menuStats
+	menuLevels
+)
 
-var gray = color.RGBA{0x88, 0x88, 0x88, 0xff}
+type Game struct {
+	sprites *sprites
+	mixer   *mixer
+	// bus passes the events of the runs on screen to sound, effects and HUD
+	bus sim.EventBus
 
-type Obstacle struct {
-	x   float64
-	y   float64
-	img *ebiten.Image
-}
+	// players holds one runner per dino on screen: one in the single player
+	// modes, two in versus.
+	players    []*sim.Runner
+	gamepadIDs []ebiten.GamepadID
+	viewport   *ebiten.Image
+	canvas     *ebiten.Image
+
+	animFrame int
+	animTick  int
+
+	layers []*layer
+	night  *nightSky
+	fx     *effects
+
+	settings *settings
 
-type Game struct {
-	playerX      float64
-	playerY      float64
-	vy           float64
-	jumpCount    int
-	onGround     bool
-	isDucking    bool
-	duckDuration float64
-
-	cactuses            []Obstacle
-	birds               []Obstacle
-	cactusSpawnTick     int
-	birdSpawnTick       int
-	birdOscillationTime float64
-
-	dinoStandFrames   []*ebiten.Image
-	dinoRunningFrames []*ebiten.Image
-	dinoDeadFrames    []*ebiten.Image
-	dinoDuckFrames    []*ebiten.Image
-	cactusFrames      []*ebiten.Image
-	birdFrames        []*ebiten.Image
-	animFrame         int
-	animTick          int
-
-	groundFrame *ebiten.Image
-	groundX     float64
-
-	cloudFrame *ebiten.Image
-	clouds     []Obstacle
-
-	score       int
 	highScore   int
 	startScreen bool
 	gameOver    bool
-	hasShield   bool
-	speedLevel  int
-
-	shieldReadyFramesLeft int
-	shieldReadyBlinkTick  int
-	shieldReadyVisible    bool
-	speedUpFramesLeft     int
-	speedUpBlinkTick      int
-	speedUpVisible        bool
-
-	lastJumpKeyPressed    bool
-	lastDuckKeyPressed    bool
-	lastRestartKeyPressed bool
-
-	audioContext *audio.Context
-	jumpPlayer   *audio.Player
-	diePlayer    *audio.Player
-	pointPlayer  *audio.Player
-	runPlayer    *audio.Player
-	shieldPlayer *audio.Player
-}
-
-func isColliding(ax, ay, aw, ah, am float64, bx, by, bw, bh, bm float64) bool {
-	ax += am
-	ay += am
-	aw -= 2 * am
-	ah -= 2 * am
-
-	bx += bm
-	by += bm
-	bw -= 2 * bm
-	bh -= 2 * bm
-
-	return ax < bx+bw &&
-		ax+aw > bx &&
-		ay < by+bh &&
-		ay+ah > by
-}
 
-func isJumpKeyPressed() bool {
-	return ebiten.IsKeyPressed(ebiten.KeySpace) || ebiten.IsKeyPressed(ebiten.KeyK)
+	menu         menuScreen
+	wallet       *storage.Wallet
+	skinCursor   int
+	skinStatus   string
+	optionCursor int
+	rebinding    bool
+	keyBuf       []ebiten.Key
+
+	achievements      *achievementTracker
+	achievementCursor int
+	profiles          profileScreen
+	stats             *statsTracker
+	statsScroll       int
+
+	mode    gameMode
+	ranked  bool
+	runDay  string
+	history *storage.History
+	lan     *lanRace
+
+	// replay records player one's inputs in the single player modes.
+	replay        *sim.Replay
+	submitURL     string
+	playerName    string
+	submitResults chan submitResult
+	submitStatus  string
+	broadcast     *broadcaster
+	// runs counts the runs started, to tell the submit results of the
+	// current one
+	runs int
+	// retried tells the run is on the course of the one before, which
+	// keeps it off the leaderboard
+	retried bool
+
+	// summary is the game-over screen of the last single player run, and
+	// tally counts what it tells while the run goes on.
+	summary        *runSummary
+	tally          runTally
+	playback       []sim.Input
+	playbackLinger int
+
+	watch *spectator
+
+	// course is the script of the course run in modeCourse, prompt its
+	// prompt on screen, and courses the records of the profile on each.
+	course     *sim.CourseScript
+	prompt     coursePrompt
+	courses    courseRecords
+	courseNews string
+	levels     levelScreen
+
+	lastRestartKeyPressed    bool
+	lastFullscreenKeyPressed bool
 }
 
-func isDuckKeyPressed() bool {
-	return ebiten.IsKeyPressed(ebiten.KeyDown) || ebiten.IsKeyPressed(ebiten.KeyJ)
-}
+const sampleRate = 44100
 
-func gameSpeedForScore(score int) float64 {
-	speed := baseGameSpeed + float64(score/gameSpeedScoreStep)*gameSpeedStep
-	if speed > maxGameSpeed {
-		return maxGameSpeed
+func (g *Game) Update() error {
+	if ebiten.IsWindowBeingClosed() {
+		if err := g.settings.save(); err != nil {
+			log.Printf("saving settings: %v", err)
+		}
+		return ebiten.Termination
 	}
-	return speed
-}
-
-const (
-	maxjumpCount    = 2
-	maxDuckDuration = 3.0 // 3s for 60 FPS
-
-	shieldReadyDurationFrames = 90
-	shieldReadyBlinkFrames    = 12
-
-	screenWidth  = 800
-	screenHeight = 600
-
-	dinoRunningWidth  = 88
-	dinoRunningHeight = 94
-	dinoDuckingWidth  = 118
-	dinoDuckingHeight = 60
-
-	dinoMargin     = float64(20)
-	obstacleMargin = float64(5)
-
-	minBirdOffset = 100
-	maxBirdOffset = 180
-
-	duckYOffset = 34
+	g.updateDisplay()
+	// M mutes anywhere but where it is typed
+	if !g.typing() && inpututil.IsKeyJustPressed(ebiten.KeyM) {
+		g.settings.Muted = !g.settings.Muted
+	}
+	g.mixer.update()
+	g.achievements.update()
+	g.updateSubmitStatus()
 
-	maxCloudsNum     = 4
-	minCloudDistance = 160.0
+	g.animTick++
+	currentSpeed := sim.BaseGameSpeed
+	if len(g.players) > 0 {
+		currentSpeed = g.players[0].Speed()
+	}
 
-	groundHeight = 100
+	for _, l := range g.layers {
+		l.Update(currentSpeed)
+	}
 
-	baseGameSpeed      = 5.0
-	maxGameSpeed       = 10.0
-	gameSpeedStep      = 0.5
-	gameSpeedScoreStep = 500
+	// the sky follows whoever is ahead, and turns back to day between runs
+	lead := 0
+	if !g.startScreen && (g.mode != modeLAN || g.lan.racing) {
+		for _, p := range g.players {
+			lead = max(lead, p.Score)
+		}
+	}
+	g.night.update(lead, currentSpeed)
 
-	sampleRate = 44100
-)
+	level := 0
+	for _, p := range g.players {
+		level = max(level, p.SpeedLevel)
+	}
+	calm := g.startScreen || g.gameOver || len(g.players) == 0 ||
+		(g.mode == modeLAN && !g.lan.racing)
+	g.mixer.setMusic(calm, level)
+	g.fx.update()
 
-func (g *Game) Update() error {
-	g.animTick++
-	currentSpeed := gameSpeedForScore(g.score)
-	speedStep := g.score / gameSpeedScoreStep
-	maxSpeedStep := int((maxGameSpeed - baseGameSpeed) / gameSpeedStep)
-	if speedStep > maxSpeedStep {
-		speedStep = maxSpeedStep
-	}
-	if speedStep > g.speedLevel {
-		g.speedLevel = speedStep
-		if speedStep > 0 {
-			g.speedUpFramesLeft = shieldReadyDurationFrames
-			g.speedUpBlinkTick = 0
-			g.speedUpVisible = true
-		}
-	}
-
-	// clouds
-	if len(g.clouds) < maxCloudsNum && rand.Intn(100) < 1 {
-		newCloud := Obstacle{
-			x:   float64(screenWidth + rand.Intn(100)),
-			y:   float64(20 + rand.Intn(100)),
-			img: g.cloudFrame,
-		}
-
-		cloudW := float64(newCloud.img.Bounds().Dx())
-		cloudH := float64(newCloud.img.Bounds().Dy())
-
-		tooClose := false
-		for _, c := range g.clouds {
-			// center distance
-			dx := (newCloud.x + cloudW/2) - (c.x + cloudW/2)
-			dy := (newCloud.y + cloudH/2) - (c.y + cloudH/2)
-			distance := math.Hypot(dx, dy)
-
-			if distance < minCloudDistance {
-				tooClose = true
-				break
-			}
-		}
+	if g.mode == modeLAN {
+		g.updateLAN()
+		return nil
+	}
 
-		if !tooClose {
-			g.clouds = append(g.clouds, newCloud)
-		}
+	if g.mode == modeWatch {
+		g.updateWatch()
+		return nil
 	}
 
-	newClouds := g.clouds[:0]
-	for _, cloud := range g.clouds {
-		cloud.x -= currentSpeed * 0.3
-		if cloud.x+float64(g.cloudFrame.Bounds().Dx()) > 0 {
-			newClouds = append(newClouds, cloud)
-		}
+	if g.mode == modeReplay {
+		g.updatePlayback()
+		return nil
 	}
-	g.clouds = newClouds
 
 	if g.startScreen {
 		if g.animTick >= 10 {
 			g.animTick = 0
-			g.animFrame = (g.animFrame + 1) % len(g.dinoStandFrames)
+			g.animFrame = (g.animFrame + 1) % len(g.sprites.dinoStand)
+		}
+
+		switch g.menu {
+		case menuSkins:
+			g.updateSkins()
+			return nil
+		case menuOptions:
+			g.updateOptions()
+			return nil
+		case menuAchievements:
+			g.updateAchievements()
+			return nil
+		case menuProfiles:
+			g.updateProfiles()
+			return nil
+		case menuStats:
+			g.updateStats()
+			return nil
+		case menuLevels:
+			g.updateLevels()
+			return nil
 		}
 
 		if ebiten.IsKeyPressed(ebiten.KeySpace) {
 			g.startScreen = false
-			g.animFrame = 0
-			g.animTick = 0
+			g.startRun(modeEndless, newSeed())
+		} else if ebiten.IsKeyPressed(ebiten.KeyH) {
+			g.startCourse(sim.Tutorial)
+		} else if inpututil.IsKeyJustPressed(ebiten.KeyL) {
+			g.openLevels()
+		} else if ebiten.IsKeyPressed(ebiten.KeyD) {
+			g.startScreen = false
+			g.startRun(modeDaily, 0)
+		} else if ebiten.IsKeyPressed(ebiten.KeyV) {
+			g.startScreen = false
+			g.startRun(modeVersus, newSeed())
+		} else if ebiten.IsKeyPressed(ebiten.KeyS) {
+			g.menu = menuSkins
+		} else if ebiten.IsKeyPressed(ebiten.KeyO) {
+			g.menu = menuOptions
+		} else if ebiten.IsKeyPressed(ebiten.KeyA) {
+			g.menu = menuAchievements
+			g.achievementCursor = 0
+		} else if inpututil.IsKeyJustPressed(ebiten.KeyP) {
+			g.openProfiles()
+		} else if ebiten.IsKeyPressed(ebiten.KeyT) {
+			g.menu = menuStats
+			g.statsScroll = 0
 		}
 		return nil
 	}
 
 	if g.gameOver {
-		if g.animTick >= 10 {
-			g.animTick = 0
-			g.animFrame = (g.animFrame + 1) % len(g.dinoDeadFrames)
+		for _, p := range g.players {
+			p.AnimateDead()
 		}
 
+		if g.summary != nil && g.mode != modeVersus && g.mode != modeCourse {
+			g.updateSummary()
+			return nil
+		}
+		if g.mode == modeCourse && g.updateCourseOver() {
+			return nil
+		}
 		restartNow := ebiten.IsKeyPressed(ebiten.KeyR) || ebiten.IsKeyPressed(ebiten.KeySpace)
 		if restartNow && !g.lastRestartKeyPressed {
-			g.playerX = 100
-			g.playerY = float64(screenHeight - groundHeight - dinoRunningHeight)
-			g.vy = 0
-			g.onGround = true
-			g.animFrame = 0
-			g.jumpCount = 0
-			g.cactuses = nil
-			g.cactusSpawnTick = 0
-			g.birdSpawnTick = 0
-			g.score = 0
-			g.hasShield = false
-			g.speedLevel = 0
-
-			g.shieldReadyFramesLeft = 0
-			g.shieldReadyBlinkTick = 0
-			g.shieldReadyVisible = false
-			g.speedUpFramesLeft = 0
-			g.speedUpBlinkTick = 0
-			g.speedUpVisible = false
-			g.gameOver = false
-			g.lastRestartKeyPressed = false
+			g.startRun(g.mode, newSeed())
 			return nil
 		}
 		g.lastRestartKeyPressed = restartNow
 		return nil
 	}
 
-	if g.shieldReadyFramesLeft > 0 {
-		g.shieldReadyFramesLeft--
-		g.shieldReadyBlinkTick++
-		if g.shieldReadyBlinkTick >= shieldReadyBlinkFrames {
-			g.shieldReadyBlinkTick = 0
-			g.shieldReadyVisible = !g.shieldReadyVisible
-		}
-	} else if g.shieldReadyVisible {
-		g.shieldReadyVisible = false
-		g.shieldReadyBlinkTick = 0
+	// a hit-stop holds the run still for a few ticks
+	if g.fx.frozen() {
+		return nil
 	}
 
-	if g.speedUpFramesLeft > 0 {
-		g.speedUpFramesLeft--
-		g.speedUpBlinkTick++
-		if g.speedUpBlinkTick >= shieldReadyBlinkFrames {
-			g.speedUpBlinkTick = 0
-			g.speedUpVisible = !g.speedUpVisible
-		}
-	} else if g.speedUpVisible {
-		g.speedUpVisible = false
-		g.speedUpBlinkTick = 0
+	// a prompt of a course may slow the game down to half speed
+	g.prompt.tick()
+	if g.prompt.slowed() && g.animTick%2 == 0 {
+		return nil
 	}
 
-	// jump
-	jumpNow := isJumpKeyPressed()
-	if jumpNow && !g.lastJumpKeyPressed && g.jumpCount < maxjumpCount {
-		g.onGround = false
-
-		if g.runPlayer.IsPlaying() {
-			g.runPlayer.Pause()
+	g.gamepadIDs = ebiten.AppendGamepadIDs(g.gamepadIDs[:0])
+	inputs := []controls{g.playerOne(), playerTwoControls}
+	alive := 0
+	for i, p := range g.players {
+		in := inputs[i].read(g.gamepadIDs)
+		if i == 0 && g.replay != nil {
+			g.replay.Record(in)
+			if g.broadcast != nil {
+				g.broadcast.tick(in)
+			}
 		}
-
-		if g.jumpCount == 0 {
-			g.vy = -10
-		} else {
-			g.vy = -9
+		p.Update(in)
+		g.observe(p)
+		if p.Score > g.highScore {
+			g.highScore = p.Score
 		}
-		g.jumpCount++
-		_ = g.jumpPlayer.Rewind()
-		g.jumpPlayer.Play()
-	}
-	g.lastJumpKeyPressed = jumpNow
-
-	g.vy += 0.5
-	g.playerY += g.vy
-	groundY := float64(screenHeight - groundHeight - dinoRunningHeight)
-	if g.playerY >= groundY {
-		g.playerY = groundY
-		g.vy = 0
-		g.onGround = true
-		g.jumpCount = 0
-	}
-
-	duckNow := isDuckKeyPressed()
-	if duckNow && g.lastDuckKeyPressed {
-		g.duckDuration += 1.0 / 60.0
-		if g.duckDuration <= maxDuckDuration {
-			g.isDucking = true
-		} else {
-			g.isDucking = false
+		if !p.Dead && !p.Finished {
+			alive++
 		}
-	} else {
-		g.isDucking = false
-		g.duckDuration = 0
 	}
-	g.lastDuckKeyPressed = duckNow
-
-	// obstacles
-	// cactus
-	g.cactusSpawnTick++
-	if g.cactusSpawnTick >= rand.Intn(100)+150 {
-		g.cactusSpawnTick = 0
 
-		img := g.cactusFrames[rand.Intn(len(g.cactusFrames))]
-		h := img.Bounds().Dy()
-
-		obcactus := Obstacle{
-			x:   float64(screenWidth),
-			y:   float64(screenHeight - groundHeight - float64(h)),
-			img: img,
+	if alive == 0 {
+		g.gameOver = true
+		// the coins of a course are the same every time, banking them
+		// would make it a coin farm. Only player one plays for the wallet,
+		// the second player of versus has none.
+		if g.mode != modeCourse {
+			g.bank(g.players[0].CoinCount)
 		}
-		g.cactuses = append(g.cactuses, obcactus)
-	}
-
-	// birds
-	g.birdSpawnTick++
-	if g.birdSpawnTick >= rand.Intn(100)+rand.Intn(50)+200 {
-		g.birdSpawnTick = 0
-
-		img := g.birdFrames[rand.Intn(len(g.birdFrames))]
+		g.lastRestartKeyPressed = ebiten.IsKeyPressed(ebiten.KeyR) ||
+			ebiten.IsKeyPressed(ebiten.KeySpace)
 
-		minOffset := minBirdOffset
-		if len(g.cactuses) > 0 {
-			lastCactus := g.cactuses[len(g.cactuses)-1]
-			cactusHeight := lastCactus.img.Bounds().Dy()
-			if cactusHeight >= 100 {
-				minOffset = 160
-			}
+		if g.mode == modeCourse {
+			g.endCourse(g.players[0])
+			return nil
 		}
 
-		randOffset := float64(rand.Intn(maxBirdOffset-minOffset)) + float64(minOffset)
-		y := float64(screenHeight - groundHeight - dinoRunningHeight - randOffset)
-
-		bird := Obstacle{
-			x:   float64(screenWidth),
-			y:   y,
-			img: img,
+		score := g.players[0].Score
+		newBest := g.mode != modeVersus && score > g.history.Best
+		if newBest {
+			g.history.Best = score
 		}
-		g.birds = append(g.birds, bird)
-	}
-
-	g.score++
-	if g.score > g.highScore {
-		g.highScore = g.score
-	}
-	if g.score%1000 == 0 {
-		_ = g.pointPlayer.Rewind()
-		g.pointPlayer.Play()
-	}
-
-	if g.score >= 1100 && (g.score-1100)%1000 == 0 && !g.hasShield {
-		g.hasShield = true
-		g.shieldReadyFramesLeft = shieldReadyDurationFrames
-		g.shieldReadyBlinkTick = 0
-		g.shieldReadyVisible = true
-		_ = g.shieldPlayer.Rewind()
-		g.shieldPlayer.Play()
-	}
-
-	// colliding
-	// cactus
-	if !g.gameOver {
-		for i := 0; i < len(g.cactuses); i++ {
-			c := g.cactuses[i]
-			w, h := c.img.Bounds().Dx(), c.img.Bounds().Dy()
-
-			dinoX := g.playerX
-			dinoY := g.playerY
-			dinoW := dinoRunningWidth
-			dinoH := dinoRunningHeight
-			if g.isDucking {
-				dinoY = dinoY + duckYOffset
-				dinoW = dinoDuckingWidth
-				dinoH = dinoDuckingHeight
-			}
-
-			margin := obstacleMargin
-			if w > 100 {
-				margin = 40
-			}
-
-			if isColliding(
-				dinoX, dinoY, float64(dinoW), float64(dinoH), dinoMargin,
-				c.x, c.y, float64(w), float64(h), margin,
-			) {
-				if g.score > g.highScore {
-					g.highScore = g.score
-				}
-				if g.hasShield {
-					g.hasShield = false
-					g.cactuses = append(g.cactuses[:i], g.cactuses[i+1:]...)
-					i--
-					continue
-				}
-				g.cactuses = append(g.cactuses[:i], g.cactuses[i+1:]...)
-				g.gameOver = true
-				g.lastRestartKeyPressed = ebiten.IsKeyPressed(ebiten.KeyR) ||
-					ebiten.IsKeyPressed(ebiten.KeySpace)
-				break
-			}
+		if g.mode == modeDaily {
+			g.history.Record(g.runDay, score, g.ranked)
 		}
-	}
-	// birds
-	if !g.gameOver {
-		for i := 0; i < len(g.birds); i++ {
-			b := g.birds[i]
-			w, h := b.img.Bounds().Dx(), b.img.Bounds().Dy()
-
-			dinoX := g.playerX
-			dinoY := g.playerY
-			dinoW := dinoRunningWidth
-			dinoH := dinoRunningHeight
-			if g.isDucking {
-				dinoY = dinoY + duckYOffset
-				dinoW = dinoDuckingWidth
-				dinoH = dinoDuckingHeight
+		if newBest || g.mode == modeDaily {
+			if err := g.history.Save(); err != nil {
+				log.Printf("saving score history: %v", err)
 			}
-			if isColliding(
-				dinoX, dinoY, float64(dinoW), float64(dinoH), dinoMargin,
-				b.x, b.y, float64(w), float64(h), obstacleMargin) {
-				if g.score > g.highScore {
-					g.highScore = g.score
-				}
-				if g.hasShield {
-					g.hasShield = false
-					g.birds = append(g.birds[:i], g.birds[i+1:]...)
-					i--
-					continue
+		}
+		if g.replay != nil {
+			g.replay.Score = score
+			g.replay.Coins = g.players[0].CoinCount
+			if newBest {
+				if err := storage.SaveReplay("best", g.replay); err != nil {
+					log.Printf("saving replay: %v", err)
 				}
-				g.birds = append(g.birds[:i], g.birds[i+1:]...)
-				g.gameOver = true
-				g.lastRestartKeyPressed = ebiten.IsKeyPressed(ebiten.KeyR) ||
-					ebiten.IsKeyPressed(ebiten.KeySpace)
-				break
 			}
+			g.submit()
+			if g.broadcast != nil {
+				g.broadcast.end(g.replay.Score)
+			}
+			g.summarize(newBest)
 		}
 	}
 
-	if g.gameOver {
-		if g.runPlayer.IsPlaying() {
-			g.runPlayer.Pause()
-		}
+	return nil
+}
 
-		_ = g.diePlayer.Rewind()
-		g.diePlayer.Play()
-		return nil
+// submit sends the finished run to the leaderboard server, if one is set.
+// Daily practice runs, courses run again and runs by custom shield rules
+// stay local.
+func (g *Game) submit() {
+	if g.submitURL == "" || (g.mode == modeDaily && !g.ranked) || g.retried || g.replay.Shield != nil {
+		return
+	}
+	sub := leaderboard.Submission{Name: g.playerName, Board: leaderboard.BoardEndless, Replay: *g.replay}
+	if g.mode == modeDaily {
+		sub.Board = leaderboard.DailyBoardPrefix + g.runDay
 	}
+	g.submitStatus = "Submitting run..."
+	run := g.runs
+	go func() {
+		res, err := leaderboard.Submit(g.submitURL, sub)
+		switch {
+		case err != nil:
+			g.submitResults <- submitResult{run, "Submission failed: " + err.Error()}
+		case res.Rank > 0:
+			g.submitResults <- submitResult{run, fmt.Sprintf("Leaderboard rank: #%d", res.Rank)}
+		default:
+			g.submitResults <- submitResult{run, "Submitted, not on the leaderboard this time"}
+		}
+	}()
+}
 
-	// move ground
-	g.groundX += currentSpeed
-	groundW := g.groundFrame.Bounds().Dx()
-	g.groundX = math.Mod(g.groundX, float64(groundW))
+// submitResult is how the submission of the run numbered run went.
+type submitResult struct {
+	run    int
+	status string
+}
 
-	// move obstacles
-	newCactuses := g.cactuses[:0]
-	for _, c := range g.cactuses {
-		c.x -= currentSpeed
-		w := c.img.Bounds().Dx()
-		if c.x+float64(w) > 0 {
-			newCactuses = append(newCactuses, c)
+// updateSubmitStatus shows how the submission of the current run went.
+// Results keep coming in whatever the player does, those of runs since
+// left behind are dropped.
+func (g *Game) updateSubmitStatus() {
+	select {
+	case res := <-g.submitResults:
+		if res.run == g.runs {
+			g.submitStatus = res.status
 		}
+	default:
 	}
-	g.cactuses = newCactuses
+}
 
-	// move birds
-	g.birdOscillationTime += 0.05
-	newBirds := g.birds[:0]
-	for i, b := range g.birds {
-		osc := math.Sin(g.birdOscillationTime + float64(i))
-		speed := currentSpeed + osc*1.5
-		b.x -= speed
-		b.y += osc * 0.5
-		w := b.img.Bounds().Dx()
-		if b.x+float64(w) > 0 {
-			newBirds = append(newBirds, b)
-		}
-	}
-	g.birds = newBirds
+// newSeed seeds a course never seen before.
+func newSeed() int64 {
+	return time.Now().UnixNano()
+}
 
-	if g.animTick >= 10 {
-		g.animTick = 0
-		g.animFrame = (g.animFrame + 1) % len(g.dinoRunningFrames)
+// startRun starts a new run in mode on the course of seed. Daily runs
+// replay the course of the day instead and only the first one of the day is
+// ranked; versus runs put two dinos on the same course and courses follow
+// their script.
+func (g *Game) startRun(mode gameMode, seed int64) {
+	g.mode = mode
+	g.runs++
+	g.ranked = false
+	if mode == modeDaily {
+		now := time.Now()
+		g.runDay = sim.DayKey(now)
+		g.ranked = !g.history.HasRanked(g.runDay)
+		seed = sim.DailySeed(now)
+	}
 
-		if g.onGround {
-			_ = g.runPlayer.Rewind()
-			g.runPlayer.Play()
-		}
+	numPlayers := 1
+	if mode == modeVersus {
+		numPlayers = 2
+	}
+	g.players = g.players[:0]
+	for range numPlayers {
+		g.players = append(g.players, sim.NewRunner(seed))
+	}
+	if mode == modeCourse {
+		g.players[0] = sim.NewCourseRunner(g.course)
+	}
 
-		for i := range g.birds {
-			b := &g.birds[i]
-			b.img = g.birdFrames[rand.Intn(len(g.birdFrames))]
+	g.replay = nil
+	g.submitStatus = ""
+	if mode == modeEndless || mode == modeDaily {
+		g.recordRun(seed)
+	}
+	// the ranked attempt of the day is used up as soon as it starts
+	if g.ranked {
+		g.history.StartRanked(g.runDay)
+		if err := g.history.Save(); err != nil {
+			log.Printf("saving score history: %v", err)
 		}
 	}
 
-	return nil
+	g.tally = runTally{}
+	g.prompt = coursePrompt{}
+	g.retried = false
+	g.gameOver = false
+	g.lastRestartKeyPressed = false
+}
+
+// recordRun puts the single player on the course of seed by the shield
+// rules of the settings, recording a replay of the run. Runs by custom rules
+// are not ranked.
+func (g *Game) recordRun(seed int64) {
+	g.replay = sim.NewReplay(seed, g.settings.shieldRules())
+	g.players[0] = g.replay.NewRunner()
+	if g.replay.Shield != nil {
+		g.ranked = false
+	}
+	if g.broadcast != nil {
+		g.broadcast.startRun(g.replay)
+	}
 }
 
 func (g *Game) Draw(screen *ebiten.Image) {
+	if g.canvas == nil {
+		g.canvas = ebiten.NewImage(sim.ScreenWidth, sim.ScreenHeight)
+	}
+	g.drawFrame(g.canvas)
+	g.mixer.drawMuted(g.canvas)
+	g.achievements.drawToast(g.canvas, g.night.ink())
+	g.present(screen)
+}
+
+// drawFrame draws everything on screen, a screenWidth x screenHeight canvas.
+func (g *Game) drawFrame(screen *ebiten.Image) {
 	// background
 	screen.Fill(color.White)
 
-	// ground
-	groundY := float64(screenHeight - groundHeight - 18)
-	groundW := g.groundFrame.Bounds().Dx()
-	for i := 0; i < 2; i++ {
-		op := &ebiten.DrawImageOptions{}
-		offsetX := -g.groundX + float64(groundW*i)
-		if i == 1 {
-			offsetX -= 5 // fix the little gap
-		}
-		op.GeoM.Translate(offsetX, groundY)
-		screen.DrawImage(g.groundFrame, op)
+	if g.startScreen && g.menu == menuSkins {
+		g.drawSkins(screen)
+		return
 	}
-
-	// clouds
-	for _, cloud := range g.clouds {
-		op := &ebiten.DrawImageOptions{}
-		op.GeoM.Translate(cloud.x, cloud.y)
-		screen.DrawImage(g.cloudFrame, op)
+	if g.startScreen && g.menu == menuOptions {
+		g.drawOptions(screen)
+		return
+	}
+	if g.startScreen && g.menu == menuAchievements {
+		g.drawAchievements(screen)
+		return
+	}
+	if g.startScreen && g.menu == menuProfiles {
+		g.drawProfiles(screen)
+		return
+	}
+	if g.startScreen && g.menu == menuStats {
+		g.drawStats(screen)
+		return
+	}
+	if g.startScreen && g.menu == menuLevels {
+		g.drawLevels(screen)
+		return
 	}
 
 	if g.startScreen {
 		screen.Fill(color.RGBA{0x30, 0x30, 0x40, 0xff})
 
-		drawDinoOpts := &ebiten.DrawImageOptions{}
-		drawDinoOpts.GeoM.Translate(float64(g.playerX), float64(g.playerY))
-		screen.DrawImage(g.dinoStandFrames[g.animFrame%len(g.dinoStandFrames)], drawDinoOpts)
-
-		face := text.NewGoXFace(basicfont.Face7x13)
+		drawDinoOpts := &colorm.DrawImageOptions{}
+		drawDinoOpts.GeoM.Translate(100, float64(sim.ScreenHeight-sim.GroundHeight-sim.DinoRunningHeight))
+		colorm.DrawImage(screen, g.sprites.dinoStand[g.animFrame%len(g.sprites.dinoStand)], g.skin().colorM(), drawDinoOpts)
 
-		startX := float64(screenWidth / 2)
-		asciiY := float64(screenHeight/2 - 140)
+		startX := float64(sim.ScreenWidth / 2)
+		asciiY := float64(sim.ScreenHeight/2 - 140)
 
-		dinoASCII := []string{
-			"    ____     ____   _   __   ____ ",
-			"   / __ \\   /  _/  / | / /  / __ \\",
-			"  / / / /   / /   /  |/ /  / / / /",
-			" / /_/ /  _/ /   / /|  /  / /_/ / ",
-			"/_____/  /___/  /_/ |_/   \\____/  ",
-			"                                 ",
-		}
-
-		for i, line := range dinoASCII {
+		for i, line := range assets.Logo {
 			lineX := startX - float64(len(line)*7/2)
 			drawLine := &text.DrawOptions{}
 			drawLine.GeoM.Translate(lineX, asciiY+float64(i*13))
@@ -593,7 +525,7 @@ func (g *Game) Draw(screen *ebiten.Image) {
 			text.Draw(screen, line, face, drawLine)
 		}
 
-		startY := float64(screenHeight/2 - 30)
+		startY := float64(sim.ScreenHeight/2 - 30)
 
 		titleText := "Press SPACE to Start"
 		titleX := startX - float64(len(titleText)*7/2)
@@ -603,255 +535,531 @@ func (g *Game) Draw(screen *ebiten.Image) {
 		drawTitle.ColorScale.ScaleWithColor(color.White)
 		text.Draw(screen, titleText, face, drawTitle)
 
-		instructionText := "SPACE/K: Jump | DOWN/J: Duck"
+		dailyText := fmt.Sprintf("D: Daily Challenge | V: Versus | S: Skins (%d coins) | O: Options | A: Achievements", g.wallet.Coins)
+		dailyX := startX - float64(len(dailyText)*7/2)
+
+		drawDaily := &text.DrawOptions{}
+		drawDaily.GeoM.Translate(dailyX, startY+20)
+		drawDaily.ColorScale.ScaleWithColor(color.White)
+		text.Draw(screen, dailyText, face, drawDaily)
+
+		instructionText := fmt.Sprintf("%s: Jump | %s: Duck | %s: Raise Shield",
+			keyNames(g.settings.JumpKeys), keyNames(g.settings.DuckKeys), keyNames(g.settings.ShieldKeys))
 		instructionX := startX - float64(len(instructionText)*7/2)
 
 		drawInstruction := &text.DrawOptions{}
-		drawInstruction.GeoM.Translate(instructionX, startY+30)
+		drawInstruction.GeoM.Translate(instructionX, startY+50)
 		drawInstruction.ColorScale.ScaleWithColor(color.White)
 		text.Draw(screen, instructionText, face, drawInstruction)
 
+		versusText := "Versus player two: W: Jump | S: Duck"
+		versusX := startX - float64(len(versusText)*7/2)
+
+		drawVersusHint := &text.DrawOptions{}
+		drawVersusHint.GeoM.Translate(versusX, startY+70)
+		drawVersusHint.ColorScale.ScaleWithColor(color.White)
+		text.Draw(screen, versusText, face, drawVersusHint)
+
+		drawCentered(screen, fmt.Sprintf("Profile: %s (P to change) | T: Stats | H: Tutorial | L: Levels", storage.CurrentProfile()), startY+100, gray)
+		if !g.courses.completed(sim.Tutorial.Name) {
+			drawCentered(screen, "New here? Press H to learn the moves in the tutorial", startY+130, color.White)
+		}
 		return
 	}
 
-	face := text.NewGoXFace(basicfont.Face7x13)
+	if g.mode == modeLAN {
+		g.drawLAN(screen)
+		return
+	}
 
-	// dino
-	drawDinoOpts := &ebiten.DrawImageOptions{}
-	drawDinoOpts.GeoM.Translate(float64(g.playerX), float64(g.playerY))
-	if g.gameOver {
-		screen.DrawImage(g.dinoDeadFrames[g.animFrame%len(g.dinoDeadFrames)], drawDinoOpts)
-	} else if g.isDucking {
-		drawDinoOpts.GeoM.Translate(0, duckYOffset)
-		screen.DrawImage(g.dinoDuckFrames[g.animFrame%len(g.dinoDuckFrames)], drawDinoOpts)
-	} else if !g.onGround {
-		if g.vy < 0 {
-			screen.DrawImage(g.dinoStandFrames[g.animFrame%len(g.dinoStandFrames)], drawDinoOpts)
-		} else {
-			screen.DrawImage(g.dinoRunningFrames[g.animFrame%len(g.dinoRunningFrames)], drawDinoOpts)
+	if g.mode == modeWatch {
+		g.drawWatch(screen)
+		return
+	}
+
+	if g.mode == modeVersus {
+		g.drawVersus(screen)
+		g.drawGameOver(screen)
+		return
+	}
+
+	p := g.players[0]
+	world := g.night.begin(screen)
+	g.drawWorld(world, p, g.skin())
+	g.night.end(screen)
+	g.drawHUD(screen, p, 10, 20, 0)
+	g.drawBanners(screen, p)
+
+	if g.mode == modeDaily {
+		dailyText := fmt.Sprintf("DAILY %s (practice)", g.runDay)
+		if g.ranked {
+			dailyText = fmt.Sprintf("DAILY %s (ranked)", g.runDay)
 		}
+		drawDailyOpts := &text.DrawOptions{}
+		drawDailyOpts.GeoM.Translate(float64(sim.ScreenWidth-len(dailyText)*7-10), 20)
+		drawDailyOpts.ColorScale.ScaleWithColor(g.night.ink())
+		text.Draw(screen, dailyText, face, drawDailyOpts)
+	}
+	if g.mode == modeReplay {
+		drawCentered(screen, "REPLAY - ESC to go back", 20, g.night.ink())
+	}
+	if g.mode == modeCourse {
+		g.drawCourse(screen, p)
+	}
+
+	g.drawGameOver(screen)
+}
+
+// drawWorld draws the clouds, background, ground, dino and obstacles of one
+// runner, its dino wearing the skin sk. Callers draw it between night.begin
+// and night.end, so it follows the day/night cycle.
+func (g *Game) drawWorld(dst *ebiten.Image, p *sim.Runner, sk skin) {
+	cv := imageCanvas{dst: dst, sprites: g.sprites}
+	p.Clouds.Draw(cv)
+	for _, l := range g.layers {
+		l.draw(cv)
+	}
+
+	// ground
+	groundY := float64(sim.ScreenHeight - sim.GroundHeight - 18)
+	groundW := g.sprites.ground.Bounds().Dx()
+	for i := 0; i < 2; i++ {
+		op := &ebiten.DrawImageOptions{}
+		offsetX := -p.GroundX + float64(groundW*i)
+		if i == 1 {
+			offsetX -= 5 // fix the little gap
+		}
+		op.GeoM.Translate(offsetX, groundY)
+		dst.DrawImage(g.sprites.ground, op)
+	}
+
+	g.drawDino(dst, p, sk, 1)
+
+	// obstacles, pickups and coins
+	for _, e := range p.Entities {
+		e.Draw(cv)
+	}
+
+	g.fx.draw(dst, p)
+}
+
+// drawDino draws the dino of one runner in the skin sk, and its shield mark.
+// Remote players are drawn as see-through ghosts with alpha below 1.
+func (g *Game) drawDino(dst *ebiten.Image, p *sim.Runner, sk skin, alpha float32) {
+	drawDinoOpts := &colorm.DrawImageOptions{}
+	drawDinoOpts.GeoM.Translate(p.PlayerX, p.PlayerY)
+	cm := sk.colorM()
+	cm.Scale(1, 1, 1, float64(alpha))
+	// a dashing dino flickers, a dino behind its raised or broken shield
+	// blinks slower
+	if p.Has(sim.PowerDash) && p.AnimTick%4 < 2 {
+		cm.Scale(1, 1, 1, 0.4)
+	} else if p.Invulnerable > 0 && p.AnimTick%8 < 4 {
+		cm.Scale(1, 1, 1, 0.4)
+	}
+	var img *ebiten.Image
+	if p.Dead {
+		img = g.sprites.dinoDead[p.AnimFrame%len(g.sprites.dinoDead)]
+	} else if p.IsDucking {
+		drawDinoOpts.GeoM.Translate(0, sim.DuckYOffset)
+		img = g.sprites.dinoDuck[p.AnimFrame%len(g.sprites.dinoDuck)]
+	} else if !p.OnGround && p.VY < 0 {
+		img = g.sprites.dinoStand[p.AnimFrame%len(g.sprites.dinoStand)]
 	} else {
-		screen.DrawImage(g.dinoRunningFrames[g.animFrame%len(g.dinoRunningFrames)], drawDinoOpts)
+		img = g.sprites.dinoRunning[p.AnimFrame%len(g.sprites.dinoRunning)]
 	}
+	colorm.DrawImage(dst, img, cm, drawDinoOpts)
 
-	if g.hasShield {
+	if p.Has(sim.PowerShield) {
 		exclaimText := "!"
-		dinoX := g.playerX
-		dinoY := g.playerY
-		dinoW := dinoRunningWidth
-		dinoH := dinoRunningHeight
-		if g.isDucking {
-			dinoY = dinoY + duckYOffset
-			dinoW = dinoDuckingWidth
-			dinoH = dinoDuckingHeight
-		}
-		exclaimX := dinoX + float64(dinoW) + 6
-		exclaimY := dinoY + float64(dinoH)/2 - 6
+		dinoX, dinoY, dinoW, dinoH := p.DinoBox()
+		exclaimX := dinoX + dinoW + 6
+		exclaimY := dinoY + dinoH/2 - 6
 		exclaimOpts := &text.DrawOptions{}
 		exclaimOpts.GeoM.Translate(exclaimX, exclaimY)
 		exclaimOpts.ColorScale.ScaleWithColor(gray)
-		text.Draw(screen, exclaimText, face, exclaimOpts)
+		exclaimOpts.ColorScale.ScaleAlpha(alpha)
+		text.Draw(dst, exclaimText, face, exclaimOpts)
 
 		exclaimBoldOpts := &text.DrawOptions{}
 		exclaimBoldOpts.GeoM.Translate(exclaimX+1, exclaimY)
 		exclaimBoldOpts.ColorScale.ScaleWithColor(gray)
-		text.Draw(screen, exclaimText, face, exclaimBoldOpts)
-	}
-
-	// obstacles
-	// cactuses
-	for _, c := range g.cactuses {
-		op := &ebiten.DrawImageOptions{}
-		op.GeoM.Translate(float64(c.x), float64(c.y))
-		screen.DrawImage(c.img, op)
-	}
-	// birds
-	for _, b := range g.birds {
-		op := &ebiten.DrawImageOptions{}
-		op.GeoM.Translate(b.x, b.y)
-		screen.DrawImage(b.img, op)
+		exclaimBoldOpts.ColorScale.ScaleAlpha(alpha)
+		text.Draw(dst, exclaimText, face, exclaimBoldOpts)
 	}
+}
 
+// drawHUD draws the score lines of one runner starting at (x, y). A non-zero
+// player number prefixes them, as in versus mode.
+func (g *Game) drawHUD(dst *ebiten.Image, p *sim.Runner, x, y float64, player int) {
 	// score
-	scoreText := fmt.Sprintf("Score: %d", g.score)
+	scoreText := fmt.Sprintf("Score: %d  Coins: %d", p.Score, p.CoinCount)
 	highScoreText := fmt.Sprintf("High Score: %d", g.highScore)
+	if player > 0 {
+		scoreText = fmt.Sprintf("P%d Score: %d  Coins: %d", player, p.Score, p.CoinCount)
+		highScoreText = ""
+		if p.Dead {
+			scoreText += " (out)"
+		}
+	}
 
 	drawScoreOpts := &text.DrawOptions{}
-	drawScoreOpts.GeoM.Translate(10, 20)
-	drawScoreOpts.ColorScale.ScaleWithColor(gray)
-	text.Draw(screen, scoreText, face, drawScoreOpts)
+	drawScoreOpts.GeoM.Translate(x, y)
+	drawScoreOpts.ColorScale.ScaleWithColor(g.night.ink())
+	text.Draw(dst, scoreText, face, drawScoreOpts)
 
 	drawHighScoreOpts := &text.DrawOptions{}
-	drawHighScoreOpts.GeoM.Translate(10, 40)
-	drawHighScoreOpts.ColorScale.ScaleWithColor(gray)
-	text.Draw(screen, highScoreText, face, drawHighScoreOpts)
+	drawHighScoreOpts.GeoM.Translate(x, y+20)
+	drawHighScoreOpts.ColorScale.ScaleWithColor(g.night.ink())
+	text.Draw(dst, highScoreText, face, drawHighScoreOpts)
 
 	// duck hint
-	if g.isDucking {
-		hint := max(3.0-g.duckDuration, 0)
+	if p.IsDucking {
+		hint := max(3.0-p.DuckDuration, 0)
 		duckHintText := fmt.Sprintf("Duck timeout: %.1fs", hint)
 		drawDuckHintOpts := &text.DrawOptions{}
-		drawDuckHintOpts.GeoM.Translate(10, 60)
-		drawDuckHintOpts.ColorScale.ScaleWithColor(gray)
-		text.Draw(screen, duckHintText, face, drawDuckHintOpts)
-	}
-
-	if g.hasShield {
-		shieldText := "Shield: READY"
-		drawShieldOpts := &text.DrawOptions{}
-		drawShieldOpts.GeoM.Translate(10, 80)
-		drawShieldOpts.ColorScale.ScaleWithColor(gray)
-		text.Draw(screen, shieldText, face, drawShieldOpts)
-	}
-
-	if g.speedUpFramesLeft > 0 && g.speedUpVisible && !g.gameOver {
-		speedUpText := "SPEED UP!"
-		levelText := fmt.Sprintf("LEVEL %d", g.speedLevel)
-		speedUpX := float64(screenWidth)/2 - float64(len(speedUpText)*7/2)
-		levelX := float64(screenWidth)/2 - float64(len(levelText)*7/2)
-		speedUpY := float64(screenHeight)/2 - 50
-		levelY := float64(screenHeight)/2 - 30
-		drawSpeedUpOpts := &text.DrawOptions{}
-		drawSpeedUpOpts.GeoM.Translate(speedUpX, speedUpY)
-		drawSpeedUpOpts.ColorScale.ScaleWithColor(gray)
-		text.Draw(screen, speedUpText, face, drawSpeedUpOpts)
-		drawLevelOpts := &text.DrawOptions{}
-		drawLevelOpts.GeoM.Translate(levelX, levelY)
-		drawLevelOpts.ColorScale.ScaleWithColor(gray)
-		text.Draw(screen, levelText, face, drawLevelOpts)
-	}
-
-	if g.shieldReadyFramesLeft > 0 && g.shieldReadyVisible && !g.gameOver {
-		shieldReadyText := "SHIELD IS READY"
-		shieldReadyX := float64(screenWidth)/2 - float64(len(shieldReadyText)*7/2)
-		shieldReadyY := float64(screenHeight)/2 - 10
-		drawShieldReadyOpts := &text.DrawOptions{}
-		drawShieldReadyOpts.GeoM.Translate(shieldReadyX, shieldReadyY)
-		drawShieldReadyOpts.ColorScale.ScaleWithColor(gray)
-		text.Draw(screen, shieldReadyText, face, drawShieldReadyOpts)
+		drawDuckHintOpts.GeoM.Translate(x, y+40)
+		drawDuckHintOpts.ColorScale.ScaleWithColor(g.night.ink())
+		text.Draw(dst, duckHintText, face, drawDuckHintOpts)
 	}
 
-	if g.gameOver {
-		red := color.RGBA{0xff, 0x00, 0x00, 0xff}
+	for i, powerText := range p.PowerHUD() {
+		drawPowerOpts := &text.DrawOptions{}
+		drawPowerOpts.GeoM.Translate(x, y+60+float64(i*20))
+		drawPowerOpts.ColorScale.ScaleWithColor(g.night.ink())
+		text.Draw(dst, powerText, face, drawPowerOpts)
+	}
+}
 
-		gameOverText := "GAME OVER"
-		gameOverX := float64(screenWidth)/2 - float64(len(gameOverText)*7/2)
+func (g *Game) drawGameOver(screen *ebiten.Image) {
+	if !g.gameOver {
+		return
+	}
+	if g.mode == modeCourse {
+		g.drawCourseOver(screen)
+		return
+	}
+	if g.summary != nil && g.mode != modeVersus {
+		g.drawSummary(screen)
+		g.drawSummaryExtras(screen)
+		return
+	}
 
-		drawGameOverOpts := &text.DrawOptions{}
-		drawGameOverOpts.GeoM.Translate(gameOverX, 60)
-		drawGameOverOpts.ColorScale.ScaleWithColor(red)
-		text.Draw(screen, gameOverText, face, drawGameOverOpts)
+	red := color.RGBA{0xff, 0x00, 0x00, 0xff}
 
-		restartY := float64(90)
-		restartText := "Press SPACE or R to Restart"
-		restartX := float64(screenWidth)/2 - float64(len(restartText)*7/2)
+	gameOverText := "GAME OVER"
+	gameOverX := float64(sim.ScreenWidth)/2 - float64(len(gameOverText)*7/2)
+	gameOverY := float64(60)
+	if g.mode == modeVersus {
+		gameOverY = float64(versusHeight - 30)
+	}
 
-		drawRestart := &text.DrawOptions{}
-		drawRestart.GeoM.Translate(restartX, restartY)
-		drawRestart.ColorScale.ScaleWithColor(gray)
-		text.Draw(screen, restartText, face, drawRestart)
+	drawGameOverOpts := &text.DrawOptions{}
+	drawGameOverOpts.GeoM.Translate(gameOverX, gameOverY)
+	drawGameOverOpts.ColorScale.ScaleWithColor(red)
+	text.Draw(screen, gameOverText, face, drawGameOverOpts)
+
+	restartY := gameOverY + 30
+	restartText := "Press SPACE or R to Restart"
+	restartX := float64(sim.ScreenWidth)/2 - float64(len(restartText)*7/2)
+
+	drawRestart := &text.DrawOptions{}
+	drawRestart.GeoM.Translate(restartX, restartY)
+	drawRestart.ColorScale.ScaleWithColor(g.night.ink())
+	text.Draw(screen, restartText, face, drawRestart)
+
+	if g.mode == modeDaily {
+		best, _ := g.history.BestOf(g.runDay)
+		streak := g.history.Streak(g.runDay)
+		dailyText := fmt.Sprintf("Today's best: %d | Streak: %d day(s)", best, streak)
+		dailyX := float64(sim.ScreenWidth)/2 - float64(len(dailyText)*7/2)
+
+		drawDaily := &text.DrawOptions{}
+		drawDaily.GeoM.Translate(dailyX, restartY+30)
+		drawDaily.ColorScale.ScaleWithColor(g.night.ink())
+		text.Draw(screen, dailyText, face, drawDaily)
 	}
-}
 
-func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
-	return screenWidth, screenHeight
+	if g.submitStatus != "" {
+		drawCentered(screen, g.submitStatus, restartY+50, g.night.ink())
+	}
+
+	if g.mode == modeVersus {
+		resultText := "DRAW!"
+		if winner := versusWinner(g.players); winner >= 0 {
+			resultText = fmt.Sprintf("PLAYER %d WINS!", winner+1)
+		}
+		resultText += fmt.Sprintf("  P1: %d | P2: %d", g.players[0].Score, g.players[1].Score)
+		resultX := float64(sim.ScreenWidth)/2 - float64(len(resultText)*7/2)
+
+		drawResult := &text.DrawOptions{}
+		drawResult.GeoM.Translate(resultX, gameOverY+15)
+		drawResult.ColorScale.ScaleWithColor(red)
+		text.Draw(screen, resultText, face, drawResult)
+	}
 }
 
 func loadSprite() *ebiten.Image {
-	img, _, err := image.Decode(bytes.NewReader(spriteSheet))
+	img, _, err := image.Decode(bytes.NewReader(assets.Sprite))
 	if err != nil {
 		panic(err)
 	}
 	return ebiten.NewImageFromImage(img)
 }
 
-func loadSoundTrack(audioCtx *audio.Context, sampleRate int, blob *bytes.Reader) *audio.Player {
-	stream, err := wav.DecodeWithSampleRate(sampleRate, blob)
-	if err != nil {
-		panic(err)
+func main() {
+	if len(os.Args) > 1 {
+		switch cmd := os.Args[1]; cmd {
+		case "host", "join":
+			if err := lanCommand(cmd, os.Args[2:]); err != nil {
+				log.Fatal(err)
+			}
+			return
+		case "watch":
+			if err := watchCommand(os.Args[2:]); err != nil {
+				log.Fatal(err)
+			}
+			return
+		case "gen-sfx":
+			if err := genSFXCommand(os.Args[2:]); err != nil {
+				log.Fatal(err)
+			}
+			return
+		case "stats":
+			if err := statsCommand(os.Args[2:], os.Stdout); err != nil {
+				log.Fatal(err)
+			}
+			return
+		case "profile":
+			if err := profileCommand(os.Args[2:]); err != nil {
+				log.Fatal(err)
+			}
+			return
+		case "server":
+			if err := leaderboard.Command(cmd, os.Args[2:]); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
 	}
-	player, err := audioCtx.NewPlayer(stream)
-	if err != nil {
-		panic(err)
+
+	submitURL := flag.String("submit", "", "leaderboard server to submit finished runs to, e.g. http://host:8080")
+	name := flag.String("name", defaultPlayerName(), "player name on the leaderboard")
+	broadcastAddr := flag.String("broadcast", "", "address to stream runs to spectators on, e.g. :9000")
+	nightMode := flag.Bool("night", true, "cycle between day and night, -night=false keeps it day (remembered)")
+	nightInterval := flag.Int("night-interval", defaultNightInterval, "score between two day/night switches (remembered)")
+	particles := flag.Float64("particles", 1, "amount of dust and bursts, from 0 to 1 (remembered)")
+	shake := flag.Float64("shake", 1, "strength of the screen shake, from 0 to 1 (remembered)")
+	hitStop := flag.Float64("hit-stop", 1, "length of the freeze when a shield breaks, from 0 to 1 (remembered)")
+	reducedMotion := flag.Bool("reduced-motion", false, "turn off every motion effect (remembered)")
+	integerScale := flag.Bool("integer-scale", false, "only scale the game by whole factors, for crisp pixels (remembered)")
+	volume := flag.Float64("volume", 1, "master volume, from 0 to 1 (remembered)")
+	mute := flag.Bool("mute", false, "start muted, M toggles it in game (remembered)")
+	music := flag.Bool("music", true, "play the music, -music=false keeps the sound effects only (remembered)")
+	noAudio := flag.Bool("no-audio", false, "do not open the sound device at all, for machines without one (remembered)")
+	profile := flag.String("profile", "", "play as this profile, created if new (remembered)")
+	flag.Parse()
+
+	if *nightInterval <= 0 {
+		log.Fatal("-night-interval must be positive")
+	}
+	for name, v := range map[string]float64{"particles": *particles, "shake": *shake, "hit-stop": *hitStop} {
+		if v < 0 || v > 1 {
+			log.Fatalf("-%s must be between 0 and 1", name)
+		}
+	}
+	if *volume < 0 || *volume > 1 {
+		log.Fatal("-volume must be between 0 and 1")
 	}
-	return player
+
+	if *profile != "" {
+		if err := storage.PlayAs(*profile); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// preferences given on the command line stick for next time
+	cfg := loadSettings()
+	changed := false
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "night":
+			cfg.Night = *nightMode
+			changed = true
+		case "night-interval":
+			cfg.NightInterval = *nightInterval
+			changed = true
+		case "particles":
+			cfg.Particles = *particles
+			changed = true
+		case "shake":
+			cfg.Shake = *shake
+			changed = true
+		case "hit-stop":
+			cfg.HitStop = *hitStop
+			changed = true
+		case "reduced-motion":
+			cfg.ReducedMotion = *reducedMotion
+			changed = true
+		case "integer-scale":
+			cfg.IntegerScale = *integerScale
+			changed = true
+		case "volume":
+			cfg.MasterVolume = *volume
+			changed = true
+		case "mute":
+			cfg.Muted = *mute
+			changed = true
+		case "music":
+			cfg.Music = *music
+			changed = true
+		case "no-audio":
+			cfg.NoAudio = *noAudio
+			changed = true
+		}
+	})
+	if changed {
+		if err := cfg.save(); err != nil {
+			log.Printf("saving settings: %v", err)
+		}
+	}
+
+	game := newGameWith(cfg)
+	game.submitURL = *submitURL
+	game.playerName = *name
+	if *broadcastAddr != "" {
+		b, err := startBroadcast(*broadcastAddr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		game.broadcast = b
+	}
+	runGame(game)
 }
 
-func main() {
+func newGame() *Game {
+	return newGameWith(loadSettings())
+}
+
+// newGameWith makes a game playing with the settings cfg.
+func newGameWith(cfg *settings) *Game {
 	sprite := loadSprite()
+	mix := newMixer(cfg)
 
-	// sound track
-	audioCtx := audio.NewContext(sampleRate)
-	jumpSoundPlayer := loadSoundTrack(audioCtx, sampleRate, bytes.NewReader(jumpWav))
-	dieSoundPlayer := loadSoundTrack(audioCtx, sampleRate, bytes.NewReader(dieWav))
-	pointSoundPlayer := loadSoundTrack(audioCtx, sampleRate, bytes.NewReader(pointWav))
-	runSoundPlayer := loadSoundTrack(audioCtx, sampleRate, bytes.NewReader(runWav))
-	shieldSoundPlayer := loadSoundTrack(audioCtx, sampleRate, bytes.NewReader(shieldWav))
+	spr := newSprites(sprite)
 
-	// ground
-	groundFrame := sprite.SubImage(image.Rect(0, 104, 2404, 104+18)).(*ebiten.Image)
+	game := &Game{
+		sprites:     spr,
+		mixer:       mix,
+		startScreen: true,
+		layers:      newLayers(backgroundLayers),
+		settings:    cfg,
+		night:       newNightSky(cfg),
+		fx:          newEffects(cfg),
 
-	// cloud
-	cloudFrame := sprite.SubImage(image.Rect(170, 0, 170+90, 0+30)).(*ebiten.Image)
+		submitResults: make(chan submitResult, 1),
 
-	// dino
-	dinoStandFrames := []*ebiten.Image{
-		sprite.SubImage(image.Rect(1336, 0, 1336+88, 0+94)).(*ebiten.Image),
-		sprite.SubImage(image.Rect(1426, 0, 1425+88, 0+94)).(*ebiten.Image),
+		lastRestartKeyPressed: false,
 	}
-	dinoRunningFrames := []*ebiten.Image{
-		sprite.SubImage(image.Rect(1514, 0, 1514+88, 0+94)).(*ebiten.Image),
-		sprite.SubImage(image.Rect(1603, 0, 1603+88, 0+94)).(*ebiten.Image),
+	game.loadProfile()
+	game.bus.Subscribe(mix.onEvent)
+	game.bus.Subscribe(game.fx.onEvent)
+	game.bus.Subscribe(sim.ShowBanners)
+	game.bus.Subscribe(game.onCourseEvent)
+	game.bus.Subscribe(func(p *sim.Runner, e sim.Event) {
+		if !game.ownRun(p) {
+			return
+		}
+		game.tally.onEvent(p, e)
+		// courses play the same every time, they are no way to farm
+		// achievements and stats
+		if game.mode != modeCourse {
+			game.achievements.onEvent(p, e)
+			game.stats.onEvent(p, e)
+		}
+	})
+	return game
+}
+
+// ownRun reports whether p is player one's run, as opposed to player two's
+// in versus, somebody else's being watched or a replay.
+func (g *Game) ownRun(p *sim.Runner) bool {
+	return g.mode != modeWatch && g.mode != modeReplay && len(g.players) > 0 && p == g.players[0]
+}
+
+// observe runs the banners of p and publishes what happened to it on its
+// last tick.
+func (g *Game) observe(p *sim.Runner) {
+	if !p.Dead {
+		p.Banners.PowerUp.Tick()
+		p.Banners.SpeedUp.Tick()
 	}
-	dinoDeadFrames := []*ebiten.Image{
-		sprite.SubImage(image.Rect(1692, 0, 1692+88, 0+94)).(*ebiten.Image),
-		sprite.SubImage(image.Rect(1781, 0, 1781+88, 0+94)).(*ebiten.Image),
+	g.bus.Publish(p)
+}
+
+func runGame(game *Game) {
+	cfg := game.settings
+	ebiten.SetWindowSize(cfg.WindowWidth, cfg.WindowHeight)
+	ebiten.SetWindowSizeLimits(minWindowWidth, minWindowHeight, -1, -1)
+	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
+	ebiten.SetFullscreen(cfg.Fullscreen)
+	// settings are saved when the window closes
+	ebiten.SetWindowClosingHandled(true)
+	ebiten.SetWindowTitle("Dino makes me feel great again!")
+	err := ebiten.RunGame(game)
+	if err == nil {
+		return
 	}
-	dinoDuckFrames := []*ebiten.Image{
-		sprite.SubImage(image.Rect(1866, 34, 1866+118, 34+60)).(*ebiten.Image),
-		sprite.SubImage(image.Rect(1984, 34, 1984+118, 34+60)).(*ebiten.Image),
+	if !game.mixer.enabled() || !isAudioError(err) {
+		log.Fatal(err)
 	}
-
-	// obstacles
-	cactusFrames := []*ebiten.Image{
-		sprite.SubImage(image.Rect(446, 2, 446+34, 2+70)).(*ebiten.Image),
-		sprite.SubImage(image.Rect(548, 2, 548+68, 2+70)).(*ebiten.Image),
-		sprite.SubImage(image.Rect(652, 2, 652+49, 2+100)).(*ebiten.Image),
-		sprite.SubImage(image.Rect(752, 2, 752+199, 2+100)).(*ebiten.Image),
+	// a sound device failing only shows up once the game runs, and takes the
+	// audio of ebiten down for good: the game starts over in silence, for
+	// the next launches too
+	log.Printf("the sound device failed, starting over without sound: %v", err)
+	cfg.NoAudio = true
+	if err := cfg.save(); err != nil {
+		log.Fatalf("saving settings: %v", err)
 	}
-	birdFrames := []*ebiten.Image{
-		sprite.SubImage(image.Rect(260, 0, 260+93, 0+69)).(*ebiten.Image),
-		sprite.SubImage(image.Rect(355, 0, 355+93, 0+69)).(*ebiten.Image),
+	if os.Getenv(silentRestartEnv) != "" {
+		log.Fatal("the sound device failed again, try -no-audio")
 	}
-	game := &Game{
-		playerX:           100,
-		playerY:           float64(screenHeight - groundHeight - 94),
-		onGround:          true,
-		dinoStandFrames:   dinoStandFrames,
-		dinoRunningFrames: dinoRunningFrames,
-		dinoDeadFrames:    dinoDeadFrames,
-		dinoDuckFrames:    dinoDuckFrames,
-		cactusFrames:      cactusFrames,
-		birdFrames:        birdFrames,
-		groundFrame:       groundFrame,
-		cloudFrame:        cloudFrame,
-		startScreen:       true,
-
-		lastJumpKeyPressed:    false,
-		lastDuckKeyPressed:    false,
-		lastRestartKeyPressed: false,
-
-		audioContext: audioCtx,
-		jumpPlayer:   jumpSoundPlayer,
-		diePlayer:    dieSoundPlayer,
-		pointPlayer:  pointSoundPlayer,
-		runPlayer:    runSoundPlayer,
-		shieldPlayer: shieldSoundPlayer,
+	if err := restart(); err != nil {
+		// the game run again told what went wrong already
+		var exit *exec.ExitError
+		if errors.As(err, &exit) {
+			os.Exit(exit.ExitCode())
+		}
+		log.Fatal(err)
 	}
+}
 
-	ebiten.SetWindowSize(screenWidth, screenHeight)
-	ebiten.SetWindowTitle("Dino makes me feel great again!")
-	if err := ebiten.RunGame(game); err != nil {
-		panic(err)
+// silentRestartEnv marks the game started over without sound, not to start
+// over again and again when the settings do not stick.
+const silentRestartEnv = "DINO_SILENT_RESTART"
+
+// otoErrorPrefix starts every error of oto, the audio library under ebiten.
+// ebiten passes them on as they are, and neither exports an error type or
+// sentinel to tell a failing sound device by, so the prefix is all there is
+// to go by. It holds for oto v3.3 (see its driver_*.go): check it again when
+// bumping oto in go.mod.
+const otoErrorPrefix = "oto: "
+
+// isAudioError reports whether err, which ended the game, comes from the
+// sound device. Nothing else the game or ebiten returns starts with
+// otoErrorPrefix, and ebiten only calls into oto while the sound is on.
+func isAudioError(err error) bool {
+	return strings.HasPrefix(err.Error(), otoErrorPrefix)
+}
+
+// restart runs the game again with the same arguments, until it exits.
+func restart() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
 	}
+	cmd := exec.Command(exe, os.Args[1:]...) // #nosec G204 -- the game itself, as it was started
+	cmd.Env = append(os.Environ(), silentRestartEnv+"=1")
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd.Run()
 }
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/audio"
+	"github.com/hajimehoshi/ebiten/v2/audio/wav"
+	text "github.com/hajimehoshi/ebiten/v2/text/v2"
+
+	"github.com/yongtenglei/dino/assets"
+	"github.com/yongtenglei/dino/sim"
+)
+
+// channel groups sounds sharing a volume setting.
+type channel int
+
+const (
+	channelSFX channel = iota
+	// channelAmbient is the background noise: the footsteps
+	channelAmbient
+	channelMusic
+	numChannels
+)
+
+const (
+	// ambientDuck is how loud the ambient channel stays while the death
+	// sound plays over it
+	ambientDuck = 0.2
+
+	volumeStep = 0.1
+)
+
+// sounds holds the sound effect players. A zero value is a silent set.
+type sounds struct {
+	jump    *audio.Player
+	die     *audio.Player
+	point   *audio.Player
+	run     *audio.Player
+	shield  *audio.Player
+	coin    *audio.Player
+	powerUp *audio.Player
+	speedUp *audio.Player
+}
+
+func playSound(p *audio.Player) {
+	if p == nil {
+		return
+	}
+	_ = p.Rewind()
+	p.Play()
+}
+
+func pauseSound(p *audio.Player) {
+	if p != nil && p.IsPlaying() {
+		p.Pause()
+	}
+}
+
+// mixer owns the sound players and keeps their volume in line with the
+// master and channel volumes of the settings. When there is no audio, on
+// purpose or because loading it failed, it hands out silent sounds.
+type mixer struct {
+	cfg      *settings
+	sfx      sounds
+	channels [numChannels][]*audio.Player
+
+	music       *musicStream
+	musicPlayer *audio.Player
+}
+
+func newMixer(cfg *settings) *mixer {
+	m := &mixer{cfg: cfg}
+	if cfg.NoAudio {
+		return m
+	}
+
+	ctx := audio.NewContext(sampleRate)
+	synth := func(ch channel, s synthSound) *audio.Player {
+		p := ctx.NewPlayerFromBytes(s.pcm(sampleRate))
+		m.channels[ch] = append(m.channels[ch], p)
+		return p
+	}
+	load := func(ch channel, blob []byte) *audio.Player {
+		p, err := loadSoundTrack(ctx, sampleRate, bytes.NewReader(blob))
+		if err != nil {
+			log.Printf("loading sound: %v", err)
+			return nil
+		}
+		m.channels[ch] = append(m.channels[ch], p)
+		return p
+	}
+	m.sfx = sounds{
+		jump:    load(channelSFX, assets.JumpWav),
+		die:     load(channelSFX, assets.DieWav),
+		point:   load(channelSFX, assets.PointWav),
+		run:     synth(channelAmbient, synthSounds.footsteps),
+		shield:  synth(channelSFX, synthSounds.shield),
+		coin:    synth(channelSFX, synthSounds.coin),
+		powerUp: synth(channelSFX, synthSounds.powerUp),
+		speedUp: synth(channelSFX, synthSounds.speedUp),
+	}
+
+	m.music = newMusicStream(sampleRate)
+	p, err := ctx.NewPlayer(m.music)
+	if err != nil {
+		log.Printf("starting music: %v", err)
+	} else {
+		m.musicPlayer = p
+		m.channels[channelMusic] = append(m.channels[channelMusic], p)
+	}
+	m.apply()
+	return m
+}
+
+func loadSoundTrack(audioCtx *audio.Context, sampleRate int, blob *bytes.Reader) (*audio.Player, error) {
+	stream, err := wav.DecodeWithSampleRate(sampleRate, blob)
+	if err != nil {
+		return nil, err
+	}
+	return audioCtx.NewPlayer(stream)
+}
+
+// onEvent is the subscriber playing the sounds of p's events.
+func (m *mixer) onEvent(_ *sim.Runner, e sim.Event) {
+	s := m.sfx
+	switch e.Kind {
+	case sim.EventJumped, sim.EventDoubleJumped:
+		pauseSound(s.run)
+		playSound(s.jump)
+	case sim.EventStride:
+		playSound(s.run)
+	case sim.EventShieldGained, sim.EventShieldRaised:
+		playSound(s.shield)
+	case sim.EventPowerUp:
+		playSound(s.powerUp)
+	case sim.EventCoin:
+		playSound(s.coin)
+	case sim.EventSpeedLevelUp:
+		playSound(s.speedUp)
+	case sim.EventMilestone:
+		playSound(s.point)
+	case sim.EventDied:
+		pauseSound(s.run)
+		playSound(s.die)
+	}
+}
+
+// enabled reports whether the game has any sound at all.
+func (m *mixer) enabled() bool {
+	return !m.cfg.NoAudio
+}
+
+// volume is the volume the players of ch play at right now.
+func (m *mixer) volume(ch channel) float64 {
+	if m.cfg.Muted {
+		return 0
+	}
+	v := m.cfg.MasterVolume
+	switch ch {
+	case channelSFX:
+		v *= m.cfg.SFXVolume
+	case channelAmbient:
+		v *= m.cfg.AmbientVolume
+		if m.sfx.die != nil && m.sfx.die.IsPlaying() {
+			v *= ambientDuck
+		}
+	case channelMusic:
+		v *= m.cfg.MusicVolume
+	}
+	return v
+}
+
+// apply sets every player to the volume of its channel.
+func (m *mixer) apply() {
+	for ch, players := range m.channels {
+		v := m.volume(channel(ch))
+		for _, p := range players {
+			p.SetVolume(v)
+		}
+	}
+}
+
+// update follows the volumes, ducking included.
+func (m *mixer) update() {
+	m.apply()
+
+	if m.musicPlayer == nil {
+		return
+	}
+	switch {
+	case m.cfg.Music && !m.musicPlayer.IsPlaying():
+		m.musicPlayer.Play()
+	case !m.cfg.Music:
+		pauseSound(m.musicPlayer)
+	}
+}
+
+// setMusic picks the music for what is on screen: calm between runs, and
+// faster and fuller as the speed level goes up.
+func (m *mixer) setMusic(calm bool, level int) {
+	if m.music != nil {
+		m.music.set(calm, level)
+	}
+}
+
+// drawMuted marks the corner of the screen while the game is muted.
+func (m *mixer) drawMuted(dst *ebiten.Image) {
+	if !m.cfg.Muted || !m.enabled() {
+		return
+	}
+	mutedText := "MUTED (M)"
+	op := &text.DrawOptions{}
+	op.GeoM.Translate(float64(sim.ScreenWidth-len(mutedText)*7-10), float64(sim.ScreenHeight-20))
+	op.ColorScale.ScaleWithColor(gray)
+	text.Draw(dst, mutedText, face, op)
+}
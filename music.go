@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/binary"
+	"math"
+	"sync/atomic"
+)
+
+// The music is a chiptune generated as it plays: a little step sequencer
+// loops over a song forever, so there is no loop point to hide. While
+// running, the tempo and the number of layers grow with the speed level.
+// On the start screen and after game over it crossfades to a calmer song.
+
+type waveform int
+
+const (
+	waveSquare waveform = iota
+	wavePulse           // a square with a 25% duty cycle, thinner
+	waveTriangle
+	waveNoise
+)
+
+// musicLayer is one instrument of the band, in the order they join in.
+type musicLayer int
+
+const (
+	layerBass musicLayer = iota
+	layerArp
+	layerLead
+	layerHat
+	numMusicLayers
+)
+
+var layerSounds = [numMusicLayers]struct {
+	wave waveform
+	gain float64
+	tau  float64 // seconds for a note to fade to a third
+}{
+	layerBass: {wave: waveSquare, gain: 0.22, tau: 0.15},
+	layerArp:  {wave: waveTriangle, gain: 0.18, tau: 0.06},
+	layerLead: {wave: wavePulse, gain: 0.12, tau: 0.12},
+	layerHat:  {wave: waveNoise, gain: 0.05, tau: 0.02},
+}
+
+// song is a loop of bars of sixteen steps each.
+type song struct {
+	bpm     float64
+	bpmStep float64 // added per speed level
+	maxBPM  float64
+	// layers play from the start, one more joins every speed level
+	layers int
+	// chords holds the MIDI notes of each bar's chord
+	chords [][]int
+	// melody holds a MIDI note per step across all bars, 0 rests
+	melody []int
+}
+
+var (
+	runSong = song{
+		bpm: 120, bpmStep: 8, maxBPM: 180,
+		layers: 2,
+		chords: [][]int{{60, 64, 67}, {57, 60, 64}, {53, 57, 60}, {55, 59, 62}},
+		melody: []int{
+			72, 0, 76, 0, 79, 0, 76, 0, 72, 0, 0, 74, 76, 0, 0, 0,
+			69, 0, 72, 0, 76, 0, 72, 0, 69, 0, 0, 71, 72, 0, 0, 0,
+			65, 0, 69, 0, 72, 0, 69, 0, 77, 0, 76, 0, 74, 0, 72, 0,
+			67, 0, 71, 0, 74, 0, 71, 0, 79, 0, 77, 0, 74, 0, 71, 0,
+		},
+	}
+	calmSong = song{
+		bpm: 84, maxBPM: 84,
+		layers: 2,
+		chords: [][]int{{57, 60, 64}, {53, 57, 60}, {48, 52, 55}, {55, 59, 62}},
+	}
+)
+
+const musicCrossfadeSeconds = 1.5
+
+func midiFreq(note int) float64 {
+	return 440 * math.Pow(2, float64(note-69)/12)
+}
+
+type voice struct {
+	wave  waveform
+	freq  float64
+	phase float64
+	env   float64
+	decay float64
+	noise uint16
+}
+
+func (v *voice) trigger(note int, rate float64, l musicLayer) {
+	s := layerSounds[l]
+	v.wave = s.wave
+	v.freq = midiFreq(note)
+	v.env = s.gain
+	v.decay = math.Exp(-1 / (rate * s.tau))
+	if v.noise == 0 {
+		v.noise = 0xace1
+	}
+}
+
+func (v *voice) sample(rate float64) float64 {
+	if v.env < 1e-4 {
+		return 0
+	}
+	v.phase += v.freq / rate
+	v.phase -= math.Floor(v.phase)
+	var out float64
+	switch v.wave {
+	case waveSquare:
+		out = 1
+		if v.phase >= 0.5 {
+			out = -1
+		}
+	case wavePulse:
+		out = 1
+		if v.phase >= 0.25 {
+			out = -1
+		}
+	case waveTriangle:
+		out = 4*math.Abs(v.phase-0.5) - 1
+	case waveNoise:
+		// 16-bit Galois LFSR, like the noise channel of old consoles
+		lsb := v.noise & 1
+		v.noise >>= 1
+		if lsb != 0 {
+			v.noise ^= 0xb400
+		}
+		out = float64(v.noise&1)*2 - 1
+	}
+	out *= v.env
+	v.env *= v.decay
+	return out
+}
+
+// sequencer plays a song step by step.
+type sequencer struct {
+	song   *song
+	step   int
+	left   float64 // samples left in the current step
+	voices [numMusicLayers]voice
+}
+
+func (s *sequencer) steps() int {
+	return len(s.song.chords) * 16
+}
+
+// next renders one sample at the tempo and layers of level.
+func (s *sequencer) next(rate float64, level int) float64 {
+	if s.left <= 0 {
+		bpm := min(s.song.bpm+s.song.bpmStep*float64(level), s.song.maxBPM)
+		s.left += rate * 60 / (bpm * 4)
+		s.play(rate, min(s.song.layers+level, int(numMusicLayers)))
+		s.step = (s.step + 1) % s.steps()
+	}
+	s.left--
+
+	var out float64
+	for i := range s.voices {
+		out += s.voices[i].sample(rate)
+	}
+	return out
+}
+
+// play triggers the notes of the current step on the first layers.
+func (s *sequencer) play(rate float64, layers int) {
+	chord := s.song.chords[s.step/16]
+	pos := s.step % 16
+	for l := range musicLayer(layers) {
+		switch l {
+		case layerBass:
+			if pos%4 == 0 {
+				s.voices[l].trigger(chord[0]-12, rate, l)
+			}
+		case layerArp:
+			s.voices[l].trigger(chord[pos%len(chord)]+12, rate, l)
+		case layerLead:
+			if s.step < len(s.song.melody) && s.song.melody[s.step] > 0 {
+				s.voices[l].trigger(s.song.melody[s.step], rate, l)
+			}
+		case layerHat:
+			if pos%2 == 1 {
+				s.voices[l].trigger(0, rate, l)
+			}
+		}
+	}
+}
+
+// musicStream is an endless 16-bit stereo stream of the music. The game
+// sets the level and the mood, the audio player reads from another
+// goroutine, hence the atomics.
+type musicStream struct {
+	rate  float64
+	level atomic.Int32
+	calm  atomic.Bool
+
+	run  sequencer
+	rest sequencer
+	fade float64 // 0 plays the run song, 1 the calm one
+}
+
+func newMusicStream(rate int) *musicStream {
+	m := &musicStream{
+		rate: float64(rate),
+		run:  sequencer{song: &runSong},
+		rest: sequencer{song: &calmSong},
+		fade: 1,
+	}
+	m.calm.Store(true)
+	return m
+}
+
+// set tells the music how fast the run goes, or that it is calm time.
+func (m *musicStream) set(calm bool, level int) {
+	m.calm.Store(calm)
+	m.level.Store(int32(min(level, math.MaxInt32))) // #nosec G115 -- clamped
+}
+
+func (m *musicStream) Read(p []byte) (int, error) {
+	level := int(m.level.Load())
+	target := 0.0
+	if m.calm.Load() {
+		target = 1
+	}
+	fadeStep := 1 / (m.rate * musicCrossfadeSeconds)
+
+	n := len(p) / 4 * 4
+	for i := 0; i < n; i += 4 {
+		switch {
+		case m.fade < target:
+			m.fade = min(m.fade+fadeStep, target)
+		case m.fade > target:
+			m.fade = max(m.fade-fadeStep, target)
+		}
+		// the run song starts over from its first bar after a break
+		var v float64
+		if m.fade < 1 {
+			v += m.run.next(m.rate, level) * (1 - m.fade)
+		} else if m.run.step != 0 {
+			m.run = sequencer{song: &runSong}
+		}
+		if m.fade > 0 {
+			v += m.rest.next(m.rate, 0) * m.fade
+		}
+		s := uint16(pcm16(max(-1, min(1, v))))
+		binary.LittleEndian.PutUint16(p[i:], s)
+		binary.LittleEndian.PutUint16(p[i+2:], s)
+	}
+	return n, nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"image/color"
+	"math/rand"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/colorm"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/yongtenglei/dino/sim"
+)
+
+const (
+	// defaultNightInterval is how far apart in score day and night switch,
+	// as in the original game.
+	defaultNightInterval = 700
+
+	// nightFadeTicks is how long the palette takes to invert.
+	nightFadeTicks = 60
+
+	numStars   = 6
+	moonRadius = 20
+	moonY      = 60
+)
+
+// star is a point of the night sky.
+type star struct {
+	x, y float64
+}
+
+// nightSky is the day/night cycle. It is purely cosmetic, runners know
+// nothing about it and it never touches the course RNG.
+type nightSky struct {
+	cfg *settings
+
+	// amount goes from 0 at day to 1 at night, the palette inverts by as much.
+	amount float64
+
+	moonX float64
+	stars []star
+
+	// layer is where the world is drawn before being inverted.
+	layer *ebiten.Image
+}
+
+func newNightSky(s *settings) *nightSky {
+	return &nightSky{cfg: s, moonX: sim.ScreenWidth - 100}
+}
+
+// isNight reports whether score falls in a night stretch: every other
+// interval, starting with the first one.
+func (n *nightSky) isNight(score int) bool {
+	return n.cfg.Night && (score/n.cfg.NightInterval)%2 == 1
+}
+
+// update fades toward day or night for the leading score and drifts the
+// sky along at speed.
+func (n *nightSky) update(score int, speed float64) {
+	target := 0.0
+	if n.isNight(score) {
+		target = 1
+	}
+	switch {
+	case n.amount < target:
+		n.amount = min(n.amount+1.0/nightFadeTicks, target)
+	case n.amount > target:
+		n.amount = max(n.amount-1.0/nightFadeTicks, target)
+	}
+
+	if n.amount == 0 {
+		// a new night gets new stars
+		n.stars = n.stars[:0]
+		return
+	}
+	if len(n.stars) == 0 {
+		for range numStars {
+			n.stars = append(n.stars, star{
+				x: float64(rand.Intn(sim.ScreenWidth)),
+				y: float64(10 + rand.Intn(sim.ScreenHeight/3)),
+			})
+		}
+	}
+
+	n.moonX -= speed * 0.05
+	if n.moonX < -2*moonRadius {
+		n.moonX = sim.ScreenWidth + 2*moonRadius
+	}
+	for i := range n.stars {
+		n.stars[i].x -= speed * 0.08
+		if n.stars[i].x < 0 {
+			n.stars[i].x += sim.ScreenWidth
+		}
+	}
+}
+
+// begin returns the image the world should be drawn on: dst itself during
+// the day, an offscreen layer while the palette is inverted.
+func (n *nightSky) begin(dst *ebiten.Image) *ebiten.Image {
+	if n.amount == 0 {
+		return dst
+	}
+	if n.layer == nil {
+		n.layer = ebiten.NewImage(sim.ScreenWidth, sim.ScreenHeight)
+	}
+	n.layer.Fill(color.White)
+	n.drawSky(n.layer)
+	return n.layer
+}
+
+// drawSky draws the moon and stars. The sprite sheet has neither, so they
+// are plain shapes, drawn dark on the layer to come out light once inverted.
+func (n *nightSky) drawSky(dst *ebiten.Image) {
+	// the gray of the sprites, faded in with the night
+	c := uint8(0x53 * n.amount)
+	ink := color.RGBA{c, c, c, uint8(0xff * n.amount)}
+
+	for _, s := range n.stars {
+		vector.DrawFilledRect(dst, float32(s.x), float32(s.y), 3, 3, ink, false)
+	}
+
+	// a crescent is a full moon with a bite taken out of it
+	vector.DrawFilledCircle(dst, float32(n.moonX), moonY, moonRadius, ink, true)
+	vector.DrawFilledCircle(dst, float32(n.moonX)+moonRadius/2, moonY-moonRadius/4, moonRadius*0.8, color.White, true)
+}
+
+// end inverts the world drawn on the layer by the night amount onto dst.
+func (n *nightSky) end(dst *ebiten.Image) {
+	if n.amount == 0 {
+		return
+	}
+	var cm colorm.ColorM
+	k := n.amount
+	cm.Scale(1-2*k, 1-2*k, 1-2*k, 1)
+	cm.Translate(k, k, k, 0)
+	colorm.DrawImage(dst, n.layer, cm, &colorm.DrawImageOptions{})
+}
+
+// ink is the text color over the world. It moves away from the background
+// as the palette inverts and flips to light halfway, so the HUD stays
+// readable during the fade too.
+func (n *nightSky) ink() color.RGBA {
+	k := n.amount
+	v := 0x88 - 0xff*k
+	if k >= 0.5 {
+		v = 0x77 + 0xff*(1-k)
+	}
+	c := uint8(max(0, min(0xff, v)))
+	return color.RGBA{c, c, c, 0xff}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// option is one line of the options screen.
+type option struct {
+	name string
+	// volume points at a setting from 0 to 1, a volume or the strength of
+	// an effect, for sliders
+	volume func(s *settings) *float64
+	// toggle points at an on/off setting
+	toggle func(s *settings) *bool
+	// keys points at a key binding
+	keys func(s *settings) *[]ebiten.Key
+}
+
+var options = []option{
+	{name: "Master", volume: func(s *settings) *float64 { return &s.MasterVolume }},
+	{name: "SFX", volume: func(s *settings) *float64 { return &s.SFXVolume }},
+	{name: "Ambient", volume: func(s *settings) *float64 { return &s.AmbientVolume }},
+	{name: "Music", volume: func(s *settings) *float64 { return &s.MusicVolume }},
+	{name: "Music on", toggle: func(s *settings) *bool { return &s.Music }},
+	{name: "Mute", toggle: func(s *settings) *bool { return &s.Muted }},
+	{name: "Night cycle", toggle: func(s *settings) *bool { return &s.Night }},
+	{name: "Particles", volume: func(s *settings) *float64 { return &s.Particles }},
+	{name: "Shake", volume: func(s *settings) *float64 { return &s.Shake }},
+	{name: "Hit-stop", volume: func(s *settings) *float64 { return &s.HitStop }},
+	{name: "Less motion", toggle: func(s *settings) *bool { return &s.ReducedMotion }},
+	{name: "Jump", keys: func(s *settings) *[]ebiten.Key { return &s.JumpKeys }},
+	{name: "Duck", keys: func(s *settings) *[]ebiten.Key { return &s.DuckKeys }},
+	{name: "Shield", keys: func(s *settings) *[]ebiten.Key { return &s.ShieldKeys }},
+}
+
+// defaultKeys are the key bindings BACKSPACE goes back to, by option name.
+var defaultKeys = map[string][]ebiten.Key{
+	"Jump":   playerOneControls.jumpKeys,
+	"Duck":   playerOneControls.duckKeys,
+	"Shield": playerOneControls.shieldKeys,
+}
+
+// updateOptions runs the options screen: UP/DOWN pick a line, LEFT/RIGHT
+// change it and ESC goes back to the start screen, saving the settings. On
+// a key binding, ENTER waits for the new key and BACKSPACE puts the default
+// keys back.
+func (g *Game) updateOptions() {
+	o := options[g.optionCursor]
+	if g.rebinding {
+		g.keyBuf = inpututil.AppendJustPressedKeys(g.keyBuf[:0])
+		for _, k := range g.keyBuf {
+			if k != ebiten.KeyEscape {
+				*o.keys(g.settings) = []ebiten.Key{k}
+			}
+			g.rebinding = false
+			break
+		}
+		return
+	}
+	switch {
+	case o.keys != nil && inpututil.IsKeyJustPressed(ebiten.KeyEnter):
+		g.rebinding = true
+	case o.keys != nil && inpututil.IsKeyJustPressed(ebiten.KeyBackspace):
+		*o.keys(g.settings) = defaultKeys[o.name]
+	case inpututil.IsKeyJustPressed(ebiten.KeyEscape):
+		g.menu = menuNone
+		if err := g.settings.save(); err != nil {
+			log.Printf("saving settings: %v", err)
+		}
+	case inpututil.IsKeyJustPressed(ebiten.KeyUp):
+		g.optionCursor = (g.optionCursor + len(options) - 1) % len(options)
+	case inpututil.IsKeyJustPressed(ebiten.KeyDown):
+		g.optionCursor = (g.optionCursor + 1) % len(options)
+	case inpututil.IsKeyJustPressed(ebiten.KeyLeft):
+		switch {
+		case o.volume != nil:
+			v := o.volume(g.settings)
+			*v = max(0, *v-volumeStep)
+		case o.toggle != nil:
+			*o.toggle(g.settings) = false
+		}
+	case inpututil.IsKeyJustPressed(ebiten.KeyRight):
+		switch {
+		case o.volume != nil:
+			v := o.volume(g.settings)
+			*v = min(1, *v+volumeStep)
+		case o.toggle != nil:
+			*o.toggle(g.settings) = true
+		}
+	}
+}
+
+func (g *Game) drawOptions(screen *ebiten.Image) {
+	screen.Fill(color.RGBA{0x30, 0x30, 0x40, 0xff})
+
+	drawCentered(screen, "OPTIONS", 100, color.White)
+	for i, o := range options {
+		var value string
+		switch {
+		case o.volume != nil:
+			v := *o.volume(g.settings)
+			filled := int(v*10 + 0.5)
+			value = fmt.Sprintf("[%s%s] %3d%%", strings.Repeat("#", filled), strings.Repeat("-", 10-filled), int(v*100+0.5))
+		case o.keys != nil && g.rebinding && i == g.optionCursor:
+			value = "press a key"
+		case o.keys != nil:
+			value = keyNames(*o.keys(g.settings))
+		case *o.toggle(g.settings):
+			value = "on"
+		default:
+			value = "off"
+		}
+		line := fmt.Sprintf("%-11s %18s", o.name, value)
+		if i == g.optionCursor {
+			line = "> " + line + " <"
+		}
+		drawCentered(screen, line, float64(150+i*20), color.White)
+	}
+	if !g.mixer.enabled() {
+		drawCentered(screen, "Sound is off (-no-audio)", float64(170+len(options)*20), color.White)
+	}
+	drawCentered(screen, "UP/DOWN: Pick | LEFT/RIGHT: Change | ESC: Back | M mutes anytime", float64(210+len(options)*20), gray)
+	drawCentered(screen, "Keys: ENTER then the new key | BACKSPACE: Default keys", float64(230+len(options)*20), gray)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math/rand"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/yongtenglei/dino/sim"
+)
+
+// layerShape is what the items of a background layer look like.
+type layerShape int
+
+const (
+	shapeSprite   layerShape = iota // a region of the sprite sheet
+	shapeMountain                   // a triangle
+	shapeHill                       // a rounded bump
+)
+
+// layerSpec describes one background layer: what its items look like, and
+// how they scroll and are scattered.
+type layerSpec struct {
+	sim.LayerSpec
+	shape layerShape
+	// shapes are filled with clr.
+	clr color.RGBA
+}
+
+// layer is a scrolling background layer. It is cosmetic and draws from
+// sceneryRand, never the course's source.
+type layer struct {
+	*sim.Layer
+	spec *layerSpec
+}
+
+// sceneryRand is the random source of the background layers.
+var sceneryRand = rand.New(rand.NewSource(time.Now().UnixNano())) // #nosec G404 -- cosmetic
+
+// newLayers sets up the layers of specs, already scattered across the
+// screen so far away layers do not start out empty.
+func newLayers(specs []layerSpec) []*layer {
+	layers := make([]*layer, len(specs))
+	for i := range specs {
+		layers[i] = &layer{Layer: sim.NewLayer(&specs[i].LayerSpec, sceneryRand), spec: &specs[i]}
+	}
+	return layers
+}
+
+// whitePixel is the source image shapes are filled from, made on first use.
+var whitePixel *ebiten.Image
+
+// draw draws the layer on cv.
+func (l *layer) draw(cv imageCanvas) {
+	s := l.spec
+	if s.shape == shapeSprite {
+		l.Draw(cv)
+		return
+	}
+
+	if whitePixel == nil {
+		img := ebiten.NewImage(3, 3)
+		img.Fill(color.White)
+		whitePixel = img.SubImage(image.Rect(1, 1, 2, 2)).(*ebiten.Image)
+	}
+
+	var path vector.Path
+	for _, it := range l.Items {
+		x, top, bottom := float32(it.X), float32(it.Y), float32(it.Y+it.H)
+		w := float32(it.W)
+		path.MoveTo(x, bottom)
+		if s.shape == shapeMountain {
+			path.LineTo(x+w/2, top)
+		} else {
+			// a quadratic curve only reaches halfway to its control point
+			path.QuadTo(x+w/2, 2*top-bottom, x+w, bottom)
+		}
+		path.LineTo(x+w, bottom)
+		path.Close()
+	}
+	vs, is := path.AppendVerticesAndIndicesForFilling(nil, nil)
+	r, g, b, a := float32(s.clr.R)/0xff, float32(s.clr.G)/0xff, float32(s.clr.B)/0xff, float32(s.clr.A)/0xff
+	for i := range vs {
+		vs[i].SrcX, vs[i].SrcY = 1, 1
+		vs[i].ColorR, vs[i].ColorG, vs[i].ColorB, vs[i].ColorA = r, g, b, a
+	}
+	cv.dst.DrawTriangles(vs, is, whitePixel, &ebiten.DrawTrianglesOptions{AntiAlias: true})
+}
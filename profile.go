@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/yongtenglei/dino/storage"
+)
+
+const profileUsage = `usage: dino profile list
+       dino profile create|use|delete NAME
+       dino profile rename OLD NEW
+       dino profile export NAME [FILE]
+       dino profile import FILE [NAME]`
+
+// profileCommand implements `dino profile`, managing the profiles from the
+// command line.
+func profileCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New(profileUsage)
+	}
+	arg := func(i int) string {
+		if i < len(args) {
+			return args[i]
+		}
+		return ""
+	}
+	switch {
+	case args[0] == "list" && len(args) == 1:
+		names, err := storage.ListProfiles()
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			mark := " "
+			if name == storage.CurrentProfile() {
+				mark = "*"
+			}
+			fmt.Println(mark, name)
+		}
+		return nil
+	case args[0] == "create" && len(args) == 2:
+		return storage.CreateProfile(args[1])
+	case args[0] == "use" && len(args) == 2:
+		return storage.UseProfile(args[1])
+	case args[0] == "delete" && len(args) == 2:
+		return storage.DeleteProfile(args[1])
+	case args[0] == "rename" && len(args) == 3:
+		return storage.RenameProfile(args[1], args[2])
+	case args[0] == "export" && (len(args) == 2 || len(args) == 3):
+		file := arg(2)
+		if file == "" {
+			file = args[1] + storage.ProfileExt
+		}
+		if err := storage.WriteProfileFile(args[1], file); err != nil {
+			return err
+		}
+		fmt.Println(file)
+		return nil
+	case args[0] == "import" && (len(args) == 2 || len(args) == 3):
+		f, err := os.Open(args[1]) // #nosec G304 -- the file the user asked for
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		name, err := storage.ImportProfile(f, arg(2))
+		if err != nil {
+			return err
+		}
+		fmt.Println("imported profile", name)
+		return nil
+	}
+	return errors.New(profileUsage)
+}
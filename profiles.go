@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"path/filepath"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+
+	"github.com/yongtenglei/dino/storage"
+)
+
+// profileScreen is the state of the profiles screen.
+type profileScreen struct {
+	names  []string
+	cursor int
+	// editing types the name of a new profile, or the new name of the
+	// selected one when renaming.
+	editing  bool
+	renaming bool
+	input    []rune
+	// deleting is the profile a first DELETE picked, a second one deletes it.
+	deleting string
+	status   string
+	chars    []rune
+}
+
+// loadProfile loads everything of the current profile but the settings.
+func (g *Game) loadProfile() {
+	g.history = storage.LoadHistory()
+	g.wallet = loadWallet()
+	g.achievements = loadAchievementTracker()
+	g.stats = &statsTracker{lifetime: loadStats()}
+	g.courses = loadCourseRecords()
+	g.highScore = g.history.Best
+}
+
+// switchProfile makes name the current profile and loads it.
+func (g *Game) switchProfile(name string) error {
+	if err := storage.UseProfile(name); err != nil {
+		return err
+	}
+	// the mixer, the night sky and the effects hold on to the settings
+	*g.settings = *loadSettings()
+	g.loadProfile()
+	return nil
+}
+
+func (g *Game) openProfiles() {
+	ps := &g.profiles
+	*ps = profileScreen{chars: ps.chars}
+	g.menu = menuProfiles
+	g.refreshProfiles()
+	ps.cursor = max(slices.Index(ps.names, storage.CurrentProfile()), 0)
+}
+
+func (g *Game) refreshProfiles() {
+	ps := &g.profiles
+	names, err := storage.ListProfiles()
+	if err != nil {
+		log.Printf("listing profiles: %v", err)
+	}
+	ps.names = names
+	ps.cursor = min(ps.cursor, max(len(names)-1, 0))
+}
+
+// updateProfiles runs the profiles screen: UP/DOWN pick a profile, ENTER
+// plays as it, N creates one, R renames, DELETE deletes, E exports and ESC
+// goes back to the start screen.
+func (g *Game) updateProfiles() {
+	ps := &g.profiles
+	if ps.editing {
+		g.updateProfileName()
+		return
+	}
+	if len(ps.names) == 0 {
+		g.menu = menuNone
+		return
+	}
+	name := ps.names[ps.cursor]
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyEscape):
+		g.menu = menuNone
+	case inpututil.IsKeyJustPressed(ebiten.KeyUp):
+		ps.cursor = (ps.cursor + len(ps.names) - 1) % len(ps.names)
+		ps.status = ""
+	case inpututil.IsKeyJustPressed(ebiten.KeyDown):
+		ps.cursor = (ps.cursor + 1) % len(ps.names)
+		ps.status = ""
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnter):
+		if err := g.switchProfile(name); err != nil {
+			ps.status = err.Error()
+			return
+		}
+		g.menu = menuNone
+	case inpututil.IsKeyJustPressed(ebiten.KeyN):
+		ps.editing, ps.renaming, ps.input = true, false, nil
+		ps.status = ""
+	case inpututil.IsKeyJustPressed(ebiten.KeyR):
+		ps.editing, ps.renaming, ps.input = true, true, []rune(name)
+		ps.status = ""
+	case inpututil.IsKeyJustPressed(ebiten.KeyDelete):
+		if ps.deleting != name {
+			ps.deleting = name
+			ps.status = fmt.Sprintf("Press DELETE again to delete %s and all its saves", name)
+			return
+		}
+		ps.deleting = ""
+		if err := storage.DeleteProfile(name); err != nil {
+			ps.status = err.Error()
+			return
+		}
+		ps.status = "Deleted " + name
+		g.refreshProfiles()
+	case inpututil.IsKeyJustPressed(ebiten.KeyE):
+		ps.status = exportProfileHere(name)
+	}
+}
+
+// exportProfileHere writes the profile name to the working directory and
+// tells where.
+func exportProfileHere(name string) string {
+	file := name + storage.ProfileExt
+	if err := storage.WriteProfileFile(name, file); err != nil {
+		return err.Error()
+	}
+	if abs, err := filepath.Abs(file); err == nil {
+		file = abs
+	}
+	return "Exported to " + file
+}
+
+// typing reports whether the keys go into a profile name or a key binding
+// right now, rather than to the game.
+func (g *Game) typing() bool {
+	return g.rebinding || (g.menu == menuProfiles && g.profiles.editing)
+}
+
+// updateProfileName takes the typing of a profile name.
+func (g *Game) updateProfileName() {
+	ps := &g.profiles
+	ps.chars = ebiten.AppendInputChars(ps.chars[:0])
+	for _, c := range ps.chars {
+		if len(ps.input) < storage.MaxProfileName && c != utf8.RuneError {
+			ps.input = append(ps.input, c)
+		}
+	}
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyEscape):
+		ps.editing = false
+		ps.status = ""
+	case inpututil.IsKeyJustPressed(ebiten.KeyBackspace) && len(ps.input) > 0:
+		ps.input = ps.input[:len(ps.input)-1]
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnter):
+		name := string(ps.input)
+		var err error
+		if ps.renaming {
+			err = storage.RenameProfile(ps.names[ps.cursor], name)
+		} else {
+			err = storage.CreateProfile(name)
+		}
+		if err != nil {
+			ps.status = err.Error()
+			return
+		}
+		ps.editing = false
+		ps.status = ""
+		g.refreshProfiles()
+		ps.cursor = max(slices.Index(ps.names, name), 0)
+	}
+}
+
+func (g *Game) drawProfiles(screen *ebiten.Image) {
+	screen.Fill(color.RGBA{0x30, 0x30, 0x40, 0xff})
+
+	ps := &g.profiles
+	drawCentered(screen, "PROFILES", 100, color.White)
+	for i, name := range ps.names {
+		line := fmt.Sprintf("%-16s", name)
+		if name == storage.CurrentProfile() {
+			line += " (playing)"
+		} else {
+			line += "          "
+		}
+		if i == ps.cursor {
+			line = "> " + line + " <"
+		}
+		drawCentered(screen, line, float64(150+i*20), color.White)
+	}
+	y := float64(170 + len(ps.names)*20)
+	if ps.editing {
+		prompt := "New profile: "
+		if ps.renaming {
+			prompt = "Rename to: "
+		}
+		drawCentered(screen, prompt+string(ps.input)+"_", y, color.White)
+		drawCentered(screen, "ENTER: OK | ESC: Cancel", y+40, gray)
+		drawCentered(screen, ps.status, y+20, color.White)
+		return
+	}
+	drawCentered(screen, ps.status, y, color.White)
+	drawCentered(screen, "UP/DOWN: Pick | ENTER: Play as | N: New | R: Rename | DELETE: Delete", y+40, gray)
+	drawCentered(screen, "E: Export to a file | ESC: Back", y+60, gray)
+	drawCentered(screen, "Import one with: dino profile import FILE", y+80, gray)
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image/color"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	text "github.com/hajimehoshi/ebiten/v2/text/v2"
+
+	"github.com/yongtenglei/dino/sim"
+)
+
+const (
+	// lanCountdownTicks is how long after the host starts a race its first
+	// tick comes, leaving the start message time to reach everyone.
+	lanCountdownTicks = 180
+
+	ghostAlpha = 0.35
+)
+
+// ghost is a remote player's dino. It runs its own copy of the course,
+// simulated locally from the inputs the player streams. As the dinos never
+// interact, a ghost simply lags behind by the network latency: its inputs
+// are applied as they arrive, which is an input delay that adapts itself.
+// They go by the tick of the run they were read on, so a late or repeated
+// message cannot shift the ghost's run.
+type ghost struct {
+	name   string
+	runner *sim.Runner
+	inputs map[int]sim.Input
+	// played is the last tick of the run the ghost went through
+	played int
+	left   bool
+}
+
+type lanDeath struct {
+	name  string
+	score int
+}
+
+// lanRace is the state of a LAN race session, shared by host and clients.
+type lanRace struct {
+	session *lanSession
+	ghosts  map[int]*ghost
+	deaths  []lanDeath
+	racing  bool
+	// startTick is the tick of the session clock the race starts on
+	startTick int
+	tick      int
+	hostLeft  bool
+
+	lastStartKeyPressed bool
+}
+
+func defaultPlayerName() string {
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	if name, err := os.Hostname(); err == nil {
+		return name
+	}
+	return "dino"
+}
+
+// lanCommand implements `dino host` and `dino join host:port`.
+func lanCommand(cmd string, args []string) error {
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	name := fs.String("name", defaultPlayerName(), "player name shown to the others")
+	var addr *string
+	if cmd == "host" {
+		addr = fs.String("addr", defaultLANAddr, "address to listen on")
+	} else {
+		fs.Usage = func() {
+			fmt.Fprintln(fs.Output(), "usage: dino join [-name NAME] host:port")
+			fs.PrintDefaults()
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var session *lanSession
+	var err error
+	if cmd == "host" {
+		session, err = hostLAN(*addr, *name)
+	} else {
+		if fs.NArg() != 1 {
+			fs.Usage()
+			os.Exit(2)
+		}
+		session, err = joinLAN(fs.Arg(0), *name)
+	}
+	if err != nil {
+		return err
+	}
+	defer session.close()
+
+	game := newGame()
+	game.startScreen = false
+	game.mode = modeLAN
+	game.lan = &lanRace{session: session, ghosts: map[int]*ghost{}}
+	runGame(game)
+	return nil
+}
+
+func (r *lanRace) handle(g *Game, m netMessage) {
+	switch m.Type {
+	case msgJoin:
+		r.ghosts[m.ID] = &ghost{name: m.Name}
+	case msgLeave:
+		if gh, ok := r.ghosts[m.ID]; ok {
+			gh.left = true
+		}
+		if !r.session.host && m.ID == 0 {
+			r.hostLeft = true
+		}
+	case msgStart:
+		g.startRace(m.Seed, m.Tick)
+	case msgInput:
+		if gh, ok := r.ghosts[m.ID]; ok && gh.runner != nil && m.Tick > gh.played {
+			gh.inputs[m.Tick] = sim.Input{Jump: m.Jump, Duck: m.Duck, Shield: m.Shield}
+		}
+	case msgDied:
+		if gh, ok := r.ghosts[m.ID]; ok {
+			r.deaths = append(r.deaths, lanDeath{name: gh.name, score: m.Score})
+		}
+	}
+}
+
+// over reports whether every dino of the current race is out.
+func (r *lanRace) over(local *sim.Runner) bool {
+	if !local.Dead {
+		return false
+	}
+	for _, gh := range r.ghosts {
+		if gh.runner != nil && !gh.left && !gh.runner.Dead {
+			return false
+		}
+	}
+	return true
+}
+
+// startRace puts every player currently in the lobby on the course of seed,
+// to set off on the tick start of the session clock.
+func (g *Game) startRace(seed int64, start int) {
+	r := g.lan
+	g.players = append(g.players[:0], sim.NewRunner(seed))
+	for id, gh := range r.ghosts {
+		if gh.left {
+			delete(r.ghosts, id)
+			continue
+		}
+		gh.runner = sim.NewRunner(seed)
+		gh.inputs = map[int]sim.Input{}
+		gh.played = 0
+	}
+	r.deaths = nil
+	r.racing = true
+	r.startTick = start
+	r.tick = 0
+	g.gameOver = false
+}
+
+func (g *Game) updateLAN() {
+	r := g.lan
+	for drained := false; !drained; {
+		select {
+		case m := <-r.session.incoming:
+			r.handle(g, m)
+		default:
+			drained = true
+		}
+	}
+
+	startNow := ebiten.IsKeyPressed(ebiten.KeySpace)
+	canStart := r.session.host && (!r.racing || g.gameOver)
+	if canStart && startNow && !r.lastStartKeyPressed {
+		seed := time.Now().UnixNano()
+		start := r.session.tick() + lanCountdownTicks
+		r.session.send(netMessage{Type: msgStart, Seed: seed, Tick: start})
+		g.startRace(seed, start)
+	}
+	r.lastStartKeyPressed = startNow
+	if !r.racing {
+		return
+	}
+
+	if r.session.tick() < r.startTick {
+		return
+	}
+
+	p := g.players[0]
+	if !p.Dead {
+		g.gamepadIDs = ebiten.AppendGamepadIDs(g.gamepadIDs[:0])
+		in := g.playerOne().read(g.gamepadIDs)
+		p.Update(in)
+		g.observe(p)
+		r.tick++
+		r.session.send(netMessage{Type: msgInput, Tick: r.tick, Jump: in.Jump, Duck: in.Duck, Shield: in.Shield})
+		if p.Dead {
+			r.session.send(netMessage{Type: msgDied, Tick: r.tick, Score: p.Score})
+			r.deaths = append(r.deaths, lanDeath{name: r.session.name, score: p.Score})
+			g.bank(p.CoinCount)
+		}
+		if p.Score > g.highScore {
+			g.highScore = p.Score
+		}
+	} else {
+		p.AnimateDead()
+	}
+
+	for _, gh := range r.ghosts {
+		if gh.runner == nil {
+			continue
+		}
+		if gh.runner.Dead {
+			gh.runner.AnimateDead()
+			continue
+		}
+		for {
+			in, ok := gh.inputs[gh.played+1]
+			if !ok || gh.runner.Dead {
+				break
+			}
+			gh.runner.Update(in)
+			delete(gh.inputs, gh.played+1)
+			gh.played++
+		}
+	}
+
+	g.gameOver = r.over(p)
+}
+
+// sortedGhostIDs keeps drawing order stable across frames.
+func (r *lanRace) sortedGhostIDs() []int {
+	ids := make([]int, 0, len(r.ghosts))
+	for id := range r.ghosts {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func drawCentered(dst *ebiten.Image, s string, y float64, clr color.Color) {
+	op := &text.DrawOptions{}
+	op.GeoM.Translate(float64(sim.ScreenWidth)/2-float64(len(s)*7/2), y)
+	op.ColorScale.ScaleWithColor(clr)
+	text.Draw(dst, s, face, op)
+}
+
+func (g *Game) drawLAN(screen *ebiten.Image) {
+	r := g.lan
+	if !r.racing {
+		g.drawLobby(screen)
+		return
+	}
+
+	p := g.players[0]
+	world := g.night.begin(screen)
+	g.drawWorld(world, p, g.skin())
+	for i, id := range r.sortedGhostIDs() {
+		gh := r.ghosts[id]
+		if gh.runner == nil || gh.left {
+			continue
+		}
+		g.drawDino(world, gh.runner, defaultSkin, ghostAlpha)
+
+		x, y, _, _ := gh.runner.DinoBox()
+		nameOpts := &text.DrawOptions{}
+		nameOpts.GeoM.Translate(x, y-15-float64(i*13))
+		nameOpts.ColorScale.ScaleWithColor(gray)
+		text.Draw(world, gh.name, face, nameOpts)
+	}
+	g.night.end(screen)
+	g.drawHUD(screen, p, 10, 20, 0)
+	g.drawBanners(screen, p)
+
+	for i, d := range r.deaths {
+		deathText := fmt.Sprintf("%s died at %d", d.name, d.score)
+		deathOpts := &text.DrawOptions{}
+		deathOpts.GeoM.Translate(float64(sim.ScreenWidth-len(deathText)*7-10), float64(20+i*20))
+		deathOpts.ColorScale.ScaleWithColor(g.night.ink())
+		text.Draw(screen, deathText, face, deathOpts)
+	}
+
+	if left := r.startTick - r.session.tick(); left > 0 {
+		drawCentered(screen, fmt.Sprintf("%d", (left-1)/60+1), float64(sim.ScreenHeight)/2-50, g.night.ink())
+	}
+
+	if g.gameOver {
+		red := color.RGBA{0xff, 0x00, 0x00, 0xff}
+		winner, best := r.session.name, p.Score
+		for _, gh := range r.ghosts {
+			if gh.runner != nil && gh.runner.Score > best {
+				winner, best = gh.name, gh.runner.Score
+			}
+		}
+		drawCentered(screen, "RACE OVER", 60, red)
+		drawCentered(screen, fmt.Sprintf("%s WINS with %d!", winner, best), 80, red)
+		if r.session.host {
+			drawCentered(screen, "Press SPACE for a rematch", 110, g.night.ink())
+		} else {
+			drawCentered(screen, "Waiting for the host...", 110, g.night.ink())
+		}
+	}
+	if r.hostLeft {
+		drawCentered(screen, "The host has left", 130, g.night.ink())
+	}
+}
+
+func (g *Game) drawLobby(screen *ebiten.Image) {
+	r := g.lan
+	drawCentered(screen, "LAN RACE", 60, g.night.ink())
+	if r.session.host {
+		drawCentered(screen, fmt.Sprintf("Hosting on %s", r.session.ln.Addr()), 90, g.night.ink())
+	} else {
+		drawCentered(screen, fmt.Sprintf("Joined as %s", r.session.name), 90, g.night.ink())
+	}
+
+	names := []string{r.session.name + " (you)"}
+	for _, id := range r.sortedGhostIDs() {
+		if gh := r.ghosts[id]; !gh.left {
+			names = append(names, gh.name)
+		}
+	}
+	for i, n := range names {
+		drawCentered(screen, n, float64(130+i*20), g.night.ink())
+	}
+
+	hint := "Waiting for the host to start..."
+	if r.session.host {
+		hint = "Press SPACE to start the race"
+	}
+	if r.hostLeft {
+		hint = "The host has left"
+	}
+	drawCentered(screen, hint, float64(150+len(names)*20), g.night.ink())
+}
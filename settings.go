@@ -0,0 +1,127 @@
+package main
+
+import (
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/yongtenglei/dino/sim"
+	"github.com/yongtenglei/dino/storage"
+)
+
+const settingsFile = "settings.json"
+
+// settings are the player's preferences, kept between launches.
+type settings struct {
+	// Night turns the day/night cycle on. NightInterval is the score
+	// distance between two switches.
+	Night         bool `json:"night"`
+	NightInterval int  `json:"night_interval"`
+
+	// Window size in device-independent pixels, as the player last left it.
+	WindowWidth  int  `json:"window_width"`
+	WindowHeight int  `json:"window_height"`
+	Fullscreen   bool `json:"fullscreen"`
+	// IntegerScale only scales the game by whole factors, for crisp pixels
+	// at the cost of wider borders.
+	IntegerScale bool `json:"integer_scale"`
+
+	// Particles, Shake and HitStop scale each motion effect, from 0 to 1:
+	// the dust and bursts, the screen shake on death and the freeze when a
+	// shield breaks. ReducedMotion turns them all off.
+	Particles     float64 `json:"particles"`
+	Shake         float64 `json:"shake"`
+	HitStop       float64 `json:"hit_stop"`
+	ReducedMotion bool    `json:"reduced_motion"`
+	// EffectsIntensity is the single strength of every effect of older
+	// settings, read once into the three above.
+	EffectsIntensity *float64 `json:"effects_intensity,omitempty"`
+
+	// Volumes from 0 to 1. The master volume scales every channel: sound
+	// effects, the ambient footsteps and the music. Muted silences
+	// everything, and NoAudio does not even open the sound device.
+	MasterVolume  float64 `json:"master_volume"`
+	SFXVolume     float64 `json:"sfx_volume"`
+	AmbientVolume float64 `json:"ambient_volume"`
+	MusicVolume   float64 `json:"music_volume"`
+	Music         bool    `json:"music"`
+	Muted         bool    `json:"muted"`
+	NoAudio       bool    `json:"no_audio"`
+
+	// JumpKeys, DuckKeys and ShieldKeys are player one's keys.
+	JumpKeys   []ebiten.Key `json:"jump_keys"`
+	DuckKeys   []ebiten.Key `json:"duck_keys"`
+	ShieldKeys []ebiten.Key `json:"shield_keys"`
+
+	// ShieldRules changes the shield economy of endless and daily runs, for
+	// house rules. Runs played by anything but the default rules are never
+	// ranked nor sent to the leaderboard.
+	ShieldRules *sim.ShieldRules `json:"shield_rules,omitempty"`
+}
+
+func loadSettings() *settings {
+	s := &settings{
+		Night:         true,
+		NightInterval: defaultNightInterval,
+		WindowWidth:   sim.ScreenWidth,
+		WindowHeight:  sim.ScreenHeight,
+
+		Particles: 1,
+		Shake:     1,
+		HitStop:   1,
+
+		MasterVolume:  1,
+		SFXVolume:     1,
+		AmbientVolume: 1,
+		MusicVolume:   0.6,
+		Music:         true,
+	}
+	if err := storage.LoadJSON(settingsFile, s); err != nil {
+		log.Printf("loading settings: %v", err)
+	}
+	if s.NightInterval <= 0 {
+		s.NightInterval = defaultNightInterval
+	}
+	if s.EffectsIntensity != nil {
+		s.Particles, s.Shake, s.HitStop = *s.EffectsIntensity, *s.EffectsIntensity, *s.EffectsIntensity
+		s.EffectsIntensity = nil
+	}
+	s.Particles = max(0, min(1, s.Particles))
+	s.Shake = max(0, min(1, s.Shake))
+	s.HitStop = max(0, min(1, s.HitStop))
+	s.MasterVolume = max(0, min(1, s.MasterVolume))
+	s.SFXVolume = max(0, min(1, s.SFXVolume))
+	s.AmbientVolume = max(0, min(1, s.AmbientVolume))
+	s.MusicVolume = max(0, min(1, s.MusicVolume))
+	if len(s.JumpKeys) == 0 {
+		s.JumpKeys = playerOneControls.jumpKeys
+	}
+	if len(s.DuckKeys) == 0 {
+		s.DuckKeys = playerOneControls.duckKeys
+	}
+	if len(s.ShieldKeys) == 0 {
+		s.ShieldKeys = playerOneControls.shieldKeys
+	}
+	if s.ShieldRules != nil {
+		if err := s.ShieldRules.Check(); err != nil {
+			log.Printf("ignoring the shield rules of the settings: %v", err)
+			s.ShieldRules = nil
+		}
+	}
+	if s.WindowWidth < minWindowWidth || s.WindowHeight < minWindowHeight {
+		s.WindowWidth, s.WindowHeight = sim.ScreenWidth, sim.ScreenHeight
+	}
+	return s
+}
+
+// shieldRules returns the shield rules runs are played by.
+func (s *settings) shieldRules() sim.ShieldRules {
+	if s.ShieldRules == nil {
+		return sim.DefaultShieldRules
+	}
+	return *s.ShieldRules
+}
+
+func (s *settings) save() error {
+	return storage.SaveJSON(settingsFile, s)
+}
@@ -0,0 +1,38 @@
+package sim
+
+import "image"
+
+// Regions of assets/sprite.png. The simulation only ever needs their sizes,
+// so runners work without any image loaded, e.g. when verifying replays.
+var (
+	GroundRect = image.Rect(0, 104, 2404, 104+18)
+	cloudRect  = image.Rect(170, 0, 170+90, 0+30)
+
+	DinoStandRects = []image.Rectangle{
+		image.Rect(1336, 0, 1336+88, 0+94),
+		image.Rect(1426, 0, 1425+88, 0+94),
+	}
+	DinoRunningRects = []image.Rectangle{
+		image.Rect(1514, 0, 1514+88, 0+94),
+		image.Rect(1603, 0, 1603+88, 0+94),
+	}
+	DinoDeadRects = []image.Rectangle{
+		image.Rect(1692, 0, 1692+88, 0+94),
+		image.Rect(1781, 0, 1781+88, 0+94),
+	}
+	DinoDuckRects = []image.Rectangle{
+		image.Rect(1866, 34, 1866+118, 34+60),
+		image.Rect(1984, 34, 1984+118, 34+60),
+	}
+
+	cactusRects = []image.Rectangle{
+		image.Rect(446, 2, 446+34, 2+70),
+		image.Rect(548, 2, 548+68, 2+70),
+		image.Rect(652, 2, 652+49, 2+100),
+		image.Rect(752, 2, 752+199, 2+100),
+	}
+	birdRects = []image.Rectangle{
+		image.Rect(260, 0, 260+93, 0+69),
+		image.Rect(355, 0, 355+93, 0+69),
+	}
+)
@@ -0,0 +1,57 @@
+package sim
+
+const (
+	bannerDurationFrames = 90
+	bannerBlinkFrames    = 12
+)
+
+// Banner is a notice blinking over the course for a moment.
+type Banner struct {
+	Text       string
+	framesLeft int
+	blinkTick  int
+	visible    bool
+}
+
+func (b *Banner) Show(text string) {
+	b.Text = text
+	b.framesLeft = bannerDurationFrames
+	b.blinkTick = 0
+	b.visible = true
+}
+
+func (b *Banner) Tick() {
+	if b.framesLeft > 0 {
+		b.framesLeft--
+		b.blinkTick++
+		if b.blinkTick >= bannerBlinkFrames {
+			b.blinkTick = 0
+			b.visible = !b.visible
+		}
+	} else if b.visible {
+		b.visible = false
+		b.blinkTick = 0
+	}
+}
+
+func (b *Banner) Showing() bool {
+	return b.framesLeft > 0 && b.visible
+}
+
+// Banners are the notices over one runner's course.
+type Banners struct {
+	PowerUp Banner
+	SpeedUp Banner
+}
+
+// ShowBanners is the Subscriber putting up the banners of p's events.
+func ShowBanners(p *Runner, e Event) {
+	switch e.Kind {
+	case EventShieldGained:
+		p.Banners.PowerUp.Show(PowerUps[PowerShield].banner)
+	case EventPowerUp:
+		p.Banners.PowerUp.Show(PowerUps[e.Value].banner)
+	case EventSpeedLevelUp:
+		p.Banners.SpeedUp.Show("SPEED UP!")
+	}
+}
@@ -0,0 +1,16 @@
+package sim
+
+import "image"
+
+// cloudLayer is the clouds drifting by in the sky, slower than the course
+// since they are far behind it. They come from the runner's own sky source,
+// so the course of a seed is the same whatever the clouds do.
+var cloudLayer = LayerSpec{
+	Rects:       []image.Rectangle{cloudRect},
+	Speed:       0.3,
+	BottomMin:   50,
+	BottomMax:   150,
+	MaxItems:    4,
+	MinGap:      160,
+	SpawnChance: 0.01,
+}
@@ -0,0 +1,103 @@
+package sim
+
+import (
+	"image/color"
+	"math"
+)
+
+const (
+	coinSize = 16
+
+	// chances, in percent, that an obstacle comes with coins
+	coinArcChance = 40
+	coinRowChance = 50
+
+	coinArcLen    = 5
+	coinArcWidth  = 160
+	coinArcHeight = 70
+	coinRowLen    = 3
+)
+
+// spawnCoinArc maybe lays coins along the arc of a jump over the cactus c.
+func (r *Runner) spawnCoinArc(c box) {
+	if r.rng.Intn(100) >= coinArcChance {
+		return
+	}
+	r.layCoinArc(c)
+}
+
+// layCoinArc lays coins along the arc of a jump over the cactus c.
+func (r *Runner) layCoinArc(c box) {
+	cx := c.x + c.w/2
+	for i := range coinArcLen {
+		t := float64(i)/(coinArcLen-1)*2 - 1 // -1 to 1 across the arc
+		r.Entities = append(r.Entities, &coin{box{
+			x: cx + t*coinArcWidth/2 - coinSize/2,
+			y: c.y - coinSize - 10 - (1-t*t)*coinArcHeight,
+			w: coinSize,
+			h: coinSize,
+		}})
+	}
+}
+
+// spawnCoinRow maybe lines coins up right under the bird b, for those who
+// dare to hop under it.
+func (r *Runner) spawnCoinRow(b box) {
+	if r.rng.Intn(100) >= coinRowChance {
+		return
+	}
+	r.layCoinRow(b)
+}
+
+// layCoinRow lines coins up right under the bird b.
+func (r *Runner) layCoinRow(b box) {
+	for i := range coinRowLen {
+		r.Entities = append(r.Entities, &coin{box{
+			x: b.x + 10 + float64(i*30),
+			y: b.y + b.h + 8,
+			w: coinSize,
+			h: coinSize,
+		}})
+	}
+}
+
+// magnetize moves o toward the dino while the magnet is on.
+func (r *Runner) magnetize(o *box) {
+	if !r.Has(PowerMagnet) {
+		return
+	}
+	dinoX, dinoY, dinoW, dinoH := r.DinoBox()
+	dx := dinoX + dinoW/2 - (o.x + o.w/2)
+	dy := dinoY + dinoH/2 - (o.y + o.h/2)
+	if d := math.Hypot(dx, dy); d < magnetRange && d > 0 {
+		o.x += dx / d * magnetPull
+		o.y += dy / d * magnetPull
+	}
+}
+
+// coin goes to the run's haul when the dino touches it.
+type coin struct {
+	box
+}
+
+func (c *coin) Update(r *Runner, dt, speed float64) {
+	c.x -= speed * dt
+	r.magnetize(&c.box)
+}
+
+func (c *coin) Bounds() box {
+	return c.box
+}
+
+func (c *coin) OnHit(r *Runner) bool {
+	r.CoinCount++
+	r.emit(EventCoin, r.CoinCount)
+	return true
+}
+
+// Draw draws the coin as a thick ring.
+func (c *coin) Draw(cv Canvas) {
+	r := c.w / 2
+	cv.Disc(c.x+r, c.y+r, r, SpriteGray)
+	cv.Disc(c.x+r, c.y+r, r/2, color.White)
+}
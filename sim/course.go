@@ -0,0 +1,237 @@
+package sim
+
+import (
+	"cmp"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+)
+
+// A course script lays a course out by hand instead of leaving it to the
+// seed: what comes along and where, in metres from the start, up to a
+// finish line. The tutorial is one.
+
+//go:embed tutorial.json
+var tutorialJSON []byte
+
+// Tutorial teaches the moves, one at a time.
+var Tutorial = mustParseCourse(tutorialJSON)
+
+func mustParseCourse(data []byte) *CourseScript {
+	c, err := ParseCourse(data)
+	if err != nil {
+		log.Fatalf("reading course: %v", err)
+	}
+	return c
+}
+
+// CourseStep is one thing on a scripted course, At metres from the start.
+// A step puts at most one thing on the course, an obstacle or a power-up,
+// and may come with any of the rest.
+type CourseStep struct {
+	At float64 `json:"at"`
+
+	// Obstacle is the name of an obstacle kind, see ObstacleKinds. Frame
+	// picks the cactus sprite, 0 to 3 from the smallest to the widest,
+	// Parts how many cacti make a cluster, and Height how high a bird flies
+	// or a power-up floats, in pixels above the ground.
+	Obstacle string  `json:"obstacle,omitempty"`
+	Frame    int     `json:"frame,omitempty"`
+	Parts    int     `json:"parts,omitempty"`
+	Height   float64 `json:"height,omitempty"`
+	// Coins arc over a ground hazard or line up under a bird.
+	Coins bool `json:"coins,omitempty"`
+	// PowerUp is the name of a power-up to pick up, see PowerUps.
+	PowerUp string `json:"powerUp,omitempty"`
+
+	// Speed sets the speed of the course from here on, in pixels a tick.
+	Speed float64 `json:"speed,omitempty"`
+
+	// Prompt tells the player something once the dino gets here, until the
+	// event named Until happens. {jump}, {duck} and {shield} stand for
+	// the keys of each. Slow runs the game in slow motion meanwhile.
+	Prompt string `json:"prompt,omitempty"`
+	Until  string `json:"until,omitempty"`
+	Slow   bool   `json:"slow,omitempty"`
+
+	kind    ObstacleKind
+	powerUp PowerUpKind
+	until   EventKind
+}
+
+// UntilEvent is the event that takes the prompt of the step down, or -1
+// if it stays up.
+func (s *CourseStep) UntilEvent() EventKind {
+	return s.until
+}
+
+// spawns reports whether the step puts something on the course.
+func (s *CourseStep) spawns() bool {
+	return s.Obstacle != "" || s.PowerUp != ""
+}
+
+// CourseScript is a whole scripted course.
+type CourseScript struct {
+	Name string `json:"name"`
+	// Speed is the speed of the course at the start, BaseGameSpeed if not
+	// set.
+	Speed  float64      `json:"speed,omitempty"`
+	Finish float64      `json:"finish"`
+	Steps  []CourseStep `json:"steps"`
+}
+
+// ParseCourse reads a course script and checks it.
+func ParseCourse(data []byte) (*CourseScript, error) {
+	var c CourseScript
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	if c.Name == "" {
+		return nil, errors.New("the course has no name")
+	}
+	if c.Finish <= 0 {
+		return nil, fmt.Errorf("course %s: no finish line", c.Name)
+	}
+	for i := range c.Steps {
+		if err := c.Steps[i].check(); err != nil {
+			return nil, fmt.Errorf("course %s, step at %gm: %w", c.Name, c.Steps[i].At, err)
+		}
+	}
+	slices.SortStableFunc(c.Steps, func(a, b CourseStep) int { return cmp.Compare(a.At, b.At) })
+	return &c, nil
+}
+
+func (s *CourseStep) check() error {
+	if s.At < 0 {
+		return errors.New("before the start")
+	}
+	if s.Obstacle != "" && s.PowerUp != "" {
+		return errors.New("an obstacle and a power-up at once")
+	}
+	if s.Obstacle != "" {
+		i := slices.IndexFunc(ObstacleKinds[:], func(spec ObstacleSpec) bool { return spec.Name == s.Obstacle })
+		if i < 0 {
+			return fmt.Errorf("unknown obstacle %q", s.Obstacle)
+		}
+		s.kind = ObstacleKind(i)
+	}
+	if s.Frame < 0 || s.Frame >= len(cactusRects) {
+		return fmt.Errorf("no cactus frame %d", s.Frame)
+	}
+	if s.Parts < 0 {
+		return fmt.Errorf("a cluster of %d cacti", s.Parts)
+	}
+	if s.PowerUp != "" {
+		i := slices.IndexFunc(PowerUps[:], func(pu PowerUp) bool { return strings.EqualFold(pu.Name, s.PowerUp) })
+		if i < 0 {
+			return fmt.Errorf("unknown power-up %q", s.PowerUp)
+		}
+		s.powerUp = PowerUpKind(i)
+	}
+	if s.Speed < 0 {
+		return errors.New("a negative speed")
+	}
+	s.until = -1
+	if s.Until != "" {
+		var ok bool
+		if s.until, ok = FindEvent(s.Until); !ok {
+			return fmt.Errorf("unknown event %q", s.Until)
+		}
+	}
+	return nil
+}
+
+// CourseRun is how far a runner got through its course script.
+type CourseRun struct {
+	Script *CourseScript
+	// spawned is the next step to put on the course, reached the next one
+	// for the dino to get to
+	spawned int
+	reached int
+	speed   float64
+}
+
+// NewCourseRunner makes a runner going through the course script c.
+func NewCourseRunner(c *CourseScript) *Runner {
+	r := NewRunner(0)
+	speed := c.Speed
+	if speed == 0 {
+		speed = BaseGameSpeed
+	}
+	r.Course = &CourseRun{Script: c, speed: speed}
+	return r
+}
+
+// runCourse puts the steps coming into sight on the course and goes
+// through those the dino got to. It reports whether the dino crossed the
+// finish line.
+func (r *Runner) runCourse() bool {
+	c := r.Course
+	steps := c.Script.Steps
+	ahead := r.Distance + ScreenWidth - r.PlayerX
+	for ; c.spawned < len(steps) && steps[c.spawned].At*PixelsPerMetre <= ahead; c.spawned++ {
+		if s := &steps[c.spawned]; s.spawns() {
+			r.spawnStep(s, r.PlayerX+s.At*PixelsPerMetre-r.Distance)
+		}
+	}
+	for ; c.reached < len(steps) && steps[c.reached].At*PixelsPerMetre <= r.Distance; c.reached++ {
+		s := &steps[c.reached]
+		if s.Speed > 0 {
+			c.speed = s.Speed
+		}
+		if s.Prompt != "" {
+			r.emit(EventPrompt, c.reached)
+		}
+	}
+	return r.Distance >= c.Script.Finish*PixelsPerMetre
+}
+
+// spawnStep puts the obstacle or power-up of the step s on the course at x.
+func (r *Runner) spawnStep(s *CourseStep, x float64) {
+	groundY := float64(ScreenHeight - GroundHeight)
+	if s.PowerUp != "" {
+		r.Entities = append(r.Entities, &pickup{
+			box:  box{x: x, y: groundY - pickupSize - s.Height, w: pickupSize, h: pickupSize},
+			kind: s.powerUp,
+		})
+		return
+	}
+	var b box
+	switch s.kind {
+	case KindCactusCluster:
+		c := &cactus{box: box{x: x}, kind: s.kind}
+		for i := range max(s.Parts, minClusterParts) {
+			frame := clusterFrames[i%len(clusterFrames)]
+			c.parts = append(c.parts, frame)
+			c.w += float64(cactusRects[frame].Dx())
+			c.h = max(c.h, float64(cactusRects[frame].Dy()))
+		}
+		c.y = groundY - c.h
+		r.Entities = append(r.Entities, c)
+		b = c.box
+	case KindCactus:
+		w, h := float64(cactusRects[s.Frame].Dx()), float64(cactusRects[s.Frame].Dy())
+		c := &cactus{box: box{x: x, y: groundY - h, w: w, h: h}, kind: s.kind, frame: s.Frame}
+		r.Entities = append(r.Entities, c)
+		b = c.box
+	case KindRock:
+		k := &rock{box: box{x: x, y: groundY - rockSize, w: rockSize, h: rockSize}}
+		r.Entities = append(r.Entities, k)
+		b = k.box
+	default:
+		w, h := float64(birdRects[0].Dx()), float64(birdRects[0].Dy())
+		bd := &bird{box: box{x: x, y: groundY - h - s.Height, w: w, h: h}, kind: s.kind}
+		r.Entities = append(r.Entities, bd)
+		if s.Coins {
+			r.layCoinRow(bd.box)
+		}
+		return
+	}
+	if s.Coins {
+		r.layCoinArc(b)
+	}
+}
@@ -0,0 +1,22 @@
+package sim
+
+import (
+	"hash/fnv"
+	"time"
+)
+
+// DayLayout is how days are written, as in the names of the daily boards.
+const DayLayout = "2006-01-02"
+
+// DayKey names the day of t.
+func DayKey(t time.Time) string {
+	return t.Format(DayLayout)
+}
+
+// DailySeed derives the course seed from the local calendar date, so every
+// player starting a daily run on the same day gets the same obstacles.
+func DailySeed(t time.Time) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte("dino-daily-" + DayKey(t)))
+	return int64(h.Sum64() >> 1)
+}
@@ -0,0 +1,105 @@
+package sim
+
+import (
+	"image"
+	"image/color"
+)
+
+// box is a rectangle on the course.
+type box struct {
+	x float64
+	y float64
+	w float64
+	h float64
+}
+
+// inset shrinks the box by m on every side.
+func (b box) inset(m float64) box {
+	return box{x: b.x + m, y: b.y + m, w: b.w - 2*m, h: b.h - 2*m}
+}
+
+// Entity is anything coming along the course: cacti, birds and the other
+// hazards, pickups and coins. They all go through the same pipeline: every
+// tick the runner moves them, drops those that left the screen and lets the
+// dino run into the rest.
+type Entity interface {
+	// Update moves the entity on by dt ticks of the course going by at
+	// speed pixels a tick.
+	Update(r *Runner, dt, speed float64)
+	// Bounds is the hit box, already shrunk by the entity's margin.
+	Bounds() box
+	Draw(c Canvas)
+	// OnHit is what the entity does to the dino running into it. It reports
+	// whether the entity is used up.
+	OnHit(r *Runner) bool
+}
+
+// SpriteGray is the gray the sprites are drawn in.
+var SpriteGray = color.RGBA{0x53, 0x53, 0x53, 0xff}
+
+// Canvas is what entities draw themselves on, the window or the terminal.
+type Canvas interface {
+	// Sprite draws the region src of the sprite sheet with its top left
+	// corner at (x, y).
+	Sprite(src image.Rectangle, x, y float64)
+	// Disc fills the circle of radius r around (x, y).
+	Disc(x, y, r float64, clr color.Color)
+	// Ring draws the outline of the circle of radius r around (x, y).
+	Ring(x, y, r, width float64, clr color.Color)
+	// Glyph writes s centered on (x, y).
+	Glyph(s string, x, y float64, clr color.Color)
+}
+
+// updateEntities moves everything on the course by dt ticks and handles
+// what the dino runs into, in the order it all spawned.
+func (r *Runner) updateEntities(dt, speed float64) {
+	kept := r.Entities[:0]
+	for _, e := range r.Entities {
+		e.Update(r, dt, speed)
+		if b := e.Bounds(); b.x+b.w > 0 {
+			kept = append(kept, e)
+		}
+	}
+	clear(r.Entities[len(kept):])
+	r.Entities = kept
+
+	dinoX, dinoY, dinoW, dinoH := r.DinoBox()
+	for i := 0; i < len(r.Entities) && !r.Dead; i++ {
+		b := r.Entities[i].Bounds()
+		if !isColliding(dinoX, dinoY, dinoW, dinoH, dinoMargin, b.x, b.y, b.w, b.h, 0) {
+			continue
+		}
+		if r.Entities[i].OnHit(r) {
+			r.Entities = append(r.Entities[:i], r.Entities[i+1:]...)
+			i--
+		}
+	}
+}
+
+// passHazard reports the hazard of kind at b once the dino got past it,
+// cleared remembering that it already did.
+func (r *Runner) passHazard(cleared *bool, b box, kind ObstacleKind) {
+	if *cleared || b.x+b.w >= r.PlayerX {
+		return
+	}
+	*cleared = true
+	r.events = append(r.events, Event{Kind: EventCleared, Value: int(kind), Width: int(b.w)})
+}
+
+// hitHazard is what running into a hazard of kind does to the dino: a dash
+// smashes it, a shield breaks on it, and without either the run is over.
+// It reports whether the hazard is gone, which it is unless the dino is
+// invulnerable and simply passes through.
+func (r *Runner) hitHazard(kind ObstacleKind) bool {
+	switch {
+	case r.Has(PowerDash):
+		r.emit(EventSmashed, 0)
+	case r.Invulnerable > 0:
+		return false
+	case r.breakShield():
+	default:
+		r.Dead = true
+		r.KilledBy = kind
+	}
+	return true
+}
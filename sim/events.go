@@ -0,0 +1,109 @@
+package sim
+
+// The simulation only says what happened on each tick, as events. Sound,
+// particles, banners and whatever else comes along listen to them on an
+// EventBus, so the rules know nothing of how a run looks or sounds, and
+// runs simulated headless, like replays being checked, stay silent simply
+// by not publishing.
+
+// EventKind is something that happened to a runner.
+type EventKind int
+
+const (
+	EventJumped EventKind = iota
+	EventDoubleJumped
+	EventLanded
+	EventDuckStarted
+	// EventDuckTimedOut is the dino standing up with duck still held
+	EventDuckTimedOut
+	// EventStride is a step of the running animation on the ground
+	EventStride
+	EventShieldGained
+	EventShieldRaised
+	EventShieldBroken
+	// EventPowerUp is any other power-up picked up, its kind in value
+	EventPowerUp
+	EventSmashed
+	// EventCleared is a hazard the dino got past, its kind in value
+	EventCleared
+	EventCoin
+	// EventSpeedLevelUp carries the new speed level
+	EventSpeedLevelUp
+	// EventMilestone carries the score, every milestoneEvery points
+	EventMilestone
+	EventDied
+	// EventPrompt is a prompt of a course script reached, the index of its
+	// step in value
+	EventPrompt
+	// EventFinished is the finish line of a course script crossed
+	EventFinished
+)
+
+const milestoneEvery = 1000
+
+// EventNames name the events in data files, such as the achievements.
+var EventNames = [...]string{
+	EventJumped:       "jumped",
+	EventDoubleJumped: "double-jumped",
+	EventLanded:       "landed",
+	EventDuckStarted:  "duck-started",
+	EventDuckTimedOut: "duck-timed-out",
+	EventStride:       "stride",
+	EventShieldGained: "shield-gained",
+	EventShieldRaised: "shield-raised",
+	EventShieldBroken: "shield-broken",
+	EventPowerUp:      "power-up",
+	EventSmashed:      "smashed",
+	EventCleared:      "cleared",
+	EventCoin:         "coin",
+	EventSpeedLevelUp: "speed-level-up",
+	EventMilestone:    "milestone",
+	EventDied:         "died",
+	EventPrompt:       "prompt",
+	EventFinished:     "finished",
+}
+
+// FindEvent looks up an event kind by name.
+func FindEvent(name string) (EventKind, bool) {
+	for k, n := range EventNames {
+		if n == name {
+			return EventKind(k), true
+		}
+	}
+	return 0, false
+}
+
+// Event is one thing that happened to a runner on its last tick.
+type Event struct {
+	Kind  EventKind
+	Value int
+	// Width is the width of a cleared hazard
+	Width int
+}
+
+// emit records an event of the current tick.
+func (r *Runner) emit(kind EventKind, value int) {
+	r.events = append(r.events, Event{Kind: kind, Value: value})
+}
+
+// Subscriber handles an event of runner p.
+type Subscriber func(p *Runner, e Event)
+
+// EventBus hands the events of simulated ticks to its subscribers, in the
+// order they subscribed.
+type EventBus struct {
+	subs []Subscriber
+}
+
+func (b *EventBus) Subscribe(s Subscriber) {
+	b.subs = append(b.subs, s)
+}
+
+// Publish passes on the events of p's last tick.
+func (b *EventBus) Publish(p *Runner) {
+	for _, e := range p.events {
+		for _, s := range b.subs {
+			s(p, e)
+		}
+	}
+}
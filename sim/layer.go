@@ -0,0 +1,108 @@
+package sim
+
+import (
+	"image"
+	"math"
+	"math/rand"
+)
+
+// LayerSpec describes a scrolling layer of scenery: how big its items are,
+// how fast it goes by and how its items are scattered.
+type LayerSpec struct {
+	// Rects are the sprite sheet regions items pick from. Without any, items
+	// are sized within MinW to MaxW by MinH to MaxH.
+	Rects      []image.Rectangle
+	MinW, MaxW float64
+	MinH, MaxH float64
+
+	// Speed is a fraction of the course speed, smaller is further away.
+	Speed float64
+	// items rest their bottom edge between BottomMin and BottomMax.
+	BottomMin, BottomMax float64
+	MaxItems             int
+	// MinGap is the least distance between the centers of two items.
+	MinGap float64
+	// SpawnChance is the chance of a new item on each tick.
+	SpawnChance float64
+}
+
+// LayerItem is one item of a layer. Frame picks its region of Rects.
+type LayerItem struct {
+	X, Y, W, H float64
+	Frame      int
+}
+
+// Layer is a scrolling layer of scenery. It draws from its own random
+// source, never the course's, so the course of a seed is the same whatever
+// the scenery does.
+type Layer struct {
+	Spec  *LayerSpec
+	Items []LayerItem
+	rnd   *rand.Rand
+}
+
+// NewLayer sets up a layer of spec, already scattered across the screen so
+// it does not start out empty.
+func NewLayer(spec *LayerSpec, rnd *rand.Rand) *Layer {
+	l := &Layer{Spec: spec, rnd: rnd}
+	for range spec.MaxItems {
+		l.place(rnd.Float64() * ScreenWidth)
+	}
+	return l
+}
+
+// place tries to add an item at x, giving up when it would crowd another.
+func (l *Layer) place(x float64) {
+	s := l.Spec
+	it := LayerItem{X: x}
+	if len(s.Rects) > 0 {
+		it.Frame = l.rnd.Intn(len(s.Rects))
+		it.W = float64(s.Rects[it.Frame].Dx())
+		it.H = float64(s.Rects[it.Frame].Dy())
+	} else {
+		it.W = s.MinW + l.rnd.Float64()*(s.MaxW-s.MinW)
+		it.H = s.MinH + l.rnd.Float64()*(s.MaxH-s.MinH)
+	}
+	it.Y = s.BottomMin + l.rnd.Float64()*(s.BottomMax-s.BottomMin) - it.H
+
+	for _, o := range l.Items {
+		dx := (it.X + it.W/2) - (o.X + o.W/2)
+		dy := (it.Y + it.H/2) - (o.Y + o.H/2)
+		if math.Hypot(dx, dy) < s.MinGap {
+			return
+		}
+	}
+	l.Items = append(l.Items, it)
+}
+
+// Update scrolls the layer for a course going by at speed pixels a tick,
+// and now and then brings in a new item from the right.
+func (l *Layer) Update(speed float64) {
+	s := l.Spec
+	if len(l.Items) < s.MaxItems && l.rnd.Float64() < s.SpawnChance {
+		l.place(float64(ScreenWidth + l.rnd.Intn(100)))
+	}
+
+	items := l.Items[:0]
+	for _, it := range l.Items {
+		it.X -= speed * s.Speed
+		if it.X+it.W > 0 {
+			items = append(items, it)
+		}
+	}
+	l.Items = items
+}
+
+// Draw draws the items of a layer of sprites.
+func (l *Layer) Draw(cv Canvas) {
+	for _, it := range l.Items {
+		cv.Sprite(l.Spec.Rects[it.Frame], it.X, it.Y)
+	}
+}
+
+// clone copies the layer, sharing its random source.
+func (l *Layer) clone() *Layer {
+	c := *l
+	c.Items = append([]LayerItem(nil), l.Items...)
+	return &c
+}
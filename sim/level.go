@@ -0,0 +1,254 @@
+package sim
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+)
+
+// Levels are hand-made courses, in the same course script format as the
+// tutorial. Some come with the game, players add their own to the levels
+// folder of the data dir.
+
+//go:embed levels/*.json
+var levelFS embed.FS
+
+const (
+	// levelCoinPoints is what a coin adds to the score of a level run
+	levelCoinPoints = 50
+)
+
+// Level is a course the player can pick on the level select screen: its
+// script and where it comes from.
+type Level struct {
+	Script  *CourseScript
+	File    string
+	BuiltIn bool
+}
+
+// LoadLevels loads the levels of the game, then those of the player in
+// dir, each sorted by file name. An empty dir loads only the game's own.
+// A level that cannot be read is left out and reported in errs.
+func LoadLevels(dir string) (levels []*Level, errs []error) {
+	files, _ := fs.Glob(levelFS, "levels/*.json")
+	for _, file := range files {
+		data, err := levelFS.ReadFile(file)
+		if err == nil {
+			var c *CourseScript
+			if c, err = ParseCourse(data); err == nil {
+				levels = append(levels, &Level{Script: c, File: path.Base(file), BuiltIn: true})
+				continue
+			}
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", file, err))
+	}
+
+	if dir == "" {
+		return levels, errs
+	}
+	files, _ = filepath.Glob(filepath.Join(dir, "*.json"))
+	for _, file := range files {
+		c, err := ReadCourse(file)
+		taken := func(l *Level) bool { return l.Script.Name == c.Name }
+		if err == nil && (c.Name == Tutorial.Name || slices.ContainsFunc(levels, taken)) {
+			err = fmt.Errorf("there already is a level called %q", c.Name)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		levels = append(levels, &Level{Script: c, File: filepath.Base(file)})
+	}
+	return levels, errs
+}
+
+// ReadCourse reads the course script in file.
+func ReadCourse(file string) (*CourseScript, error) {
+	data, err := os.ReadFile(file) // #nosec G304 -- a level the player put there
+	if err != nil {
+		return nil, err
+	}
+	c, err := ParseCourse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(file), err)
+	}
+	return c, nil
+}
+
+// CourseScore is the score of a run of a scripted course, coins included.
+func CourseScore(p *Runner) int {
+	return p.Score + p.CoinCount*levelCoinPoints
+}
+
+const (
+	// the solver picks a move every solveStep ticks, from those that make a
+	// difference with a hazard within solveLookahead pixels
+	solveStep      = 4
+	solveLookahead = 450
+	// solveBudget bounds the ticks the solver simulates before giving up
+	solveBudget = 2_000_000
+)
+
+// move is what the solver does for a step.
+type move int
+
+const (
+	moveRun move = iota
+	moveJump
+	moveDuck
+	moveShield
+)
+
+// input is the controls of tick i of a step of the move m. Jump and
+// shield are let go after a tick, so the next step can press them again.
+func (m move) input(i int) Input {
+	switch m {
+	case moveJump:
+		return Input{Jump: i == 0}
+	case moveDuck:
+		return Input{Duck: true}
+	case moveShield:
+		return Input{Shield: i == 0}
+	}
+	return Input{}
+}
+
+// solveState is what sets apart two runs of the same course at the same
+// tick, for the solver not to try a dead end twice.
+type solveState struct {
+	ticks, entities         int
+	distance, y, vy         float64
+	jumps                   int
+	ducking, duckHeld       bool
+	duckTicks, invulnerable int
+	powers                  [NumPowerUps]powerState
+}
+
+func stateOf(r *Runner) solveState {
+	return solveState{
+		ticks:        r.Score,
+		entities:     len(r.Entities),
+		distance:     r.Distance,
+		y:            r.PlayerY,
+		vy:           r.VY,
+		jumps:        r.JumpCount,
+		ducking:      r.IsDucking,
+		duckHeld:     r.lastDuckKeyPressed,
+		duckTicks:    int(r.DuckDuration * 60),
+		invulnerable: r.Invulnerable,
+		powers:       r.powers,
+	}
+}
+
+// solver looks for inputs getting a runner across the finish line of its
+// course, trying moves depth first.
+type solver struct {
+	budget int
+	// dead ends are the states no move gets past
+	deadEnds map[solveState]bool
+}
+
+// SolveCourse plays the course script c headless, looking for a way to the
+// finish line. It returns the inputs of a run crossing it, or false when it
+// found none or gave up.
+func SolveCourse(c *CourseScript) ([]Input, bool) {
+	s := &solver{budget: solveBudget, deadEnds: map[solveState]bool{}}
+	return s.solve(NewCourseRunner(c), nil)
+}
+
+func (s *solver) solve(r *Runner, inputs []Input) ([]Input, bool) {
+	if r.Finished {
+		return inputs, true
+	}
+	if r.Dead || s.budget <= 0 {
+		return nil, false
+	}
+	state := stateOf(r)
+	if s.deadEnds[state] {
+		return nil, false
+	}
+	for _, m := range s.moves(r) {
+		next := r.clone()
+		n := len(inputs)
+		for i := 0; i < solveStep && !next.Dead && !next.Finished; i++ {
+			in := m.input(i)
+			next.Update(in)
+			inputs = append(inputs, in)
+			s.budget--
+		}
+		if won, ok := s.solve(next, inputs); ok {
+			return won, true
+		}
+		inputs = inputs[:n]
+	}
+	if s.budget > 0 {
+		s.deadEnds[state] = true
+	}
+	return nil, false
+}
+
+// moves lists the moves worth trying for r, running first.
+func (s *solver) moves(r *Runner) []move {
+	near := false
+	for _, e := range r.Entities {
+		switch e.(type) {
+		case *cactus, *rock, *bird:
+			b := e.Bounds()
+			near = near || (b.x+b.w > r.PlayerX && b.x < r.PlayerX+solveLookahead)
+		}
+	}
+	if !near {
+		return []move{moveRun}
+	}
+	moves := []move{moveRun}
+	if r.JumpCount < r.maxJumps() {
+		moves = append(moves, moveJump)
+	}
+	moves = append(moves, moveDuck)
+	if r.Has(PowerShield) && r.Invulnerable == 0 {
+		moves = append(moves, moveShield)
+	}
+	return moves
+}
+
+// clone copies r and its course to simulate on without touching r. The
+// random sources are shared, scripted courses only draw clouds from them.
+func (r *Runner) clone() *Runner {
+	c := *r
+	c.events = nil
+	c.Clouds = r.Clouds.clone()
+	c.Entities = make([]Entity, len(r.Entities))
+	for i, e := range r.Entities {
+		c.Entities[i] = cloneEntity(e)
+	}
+	if r.Course != nil {
+		cr := *r.Course
+		c.Course = &cr
+	}
+	return &c
+}
+
+func cloneEntity(e Entity) Entity {
+	switch e := e.(type) {
+	case *cactus:
+		c := *e
+		return &c
+	case *rock:
+		k := *e
+		return &k
+	case *bird:
+		b := *e
+		return &b
+	case *coin:
+		c := *e
+		return &c
+	case *pickup:
+		pk := *e
+		return &pk
+	}
+	panic(fmt.Sprintf("cloning unknown entity %T", e))
+}
@@ -0,0 +1,72 @@
+package sim
+
+import (
+	"io/fs"
+	"testing"
+)
+
+// TestLevelsBeatable plays the tutorial and every level of the game
+// headless, to the finish line.
+func TestLevelsBeatable(t *testing.T) {
+	scripts := []*CourseScript{Tutorial}
+	files, err := fs.Glob(levelFS, "levels/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		data, err := levelFS.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := ParseCourse(data)
+		if err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		scripts = append(scripts, c)
+	}
+
+	for _, c := range scripts {
+		inputs, ok := SolveCourse(c)
+		if !ok {
+			t.Errorf("%s: found no way to the finish line", c.Name)
+			continue
+		}
+		// the inputs found play out the same from the start
+		r := NewCourseRunner(c)
+		for _, in := range inputs {
+			r.Update(in)
+		}
+		if !r.Finished {
+			t.Errorf("%s: the solution does not replay to the finish line", c.Name)
+		}
+	}
+}
+
+func TestSolverGivesUp(t *testing.T) {
+	c, err := ParseCourse([]byte(`{"name": "Wall", "finish": 40, "steps": [
+		{"at": 20, "obstacle": "cactus cluster", "parts": 10}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := SolveCourse(c); ok {
+		t.Error("solved a course walled off by ten cacti")
+	}
+}
+
+func TestParseCourseErrors(t *testing.T) {
+	tests := []string{
+		`{"finish": 10}`,
+		`{"name": "No finish"}`,
+		`{"name": "X", "finish": 10, "steps": [{"at": 5, "obstacle": "dragon"}]}`,
+		`{"name": "X", "finish": 10, "steps": [{"at": 5, "powerUp": "wings"}]}`,
+		`{"name": "X", "finish": 10, "steps": [{"at": 5, "obstacle": "cactus", "powerUp": "dash"}]}`,
+		`{"name": "X", "finish": 10, "steps": [{"at": 5, "obstacle": "cactus", "frame": 4}]}`,
+		`{"name": "X", "finish": 10, "steps": [{"at": 5, "prompt": "hi", "until": "flew"}]}`,
+	}
+	for _, data := range tests {
+		if _, err := ParseCourse([]byte(data)); err == nil {
+			t.Errorf("ParseCourse(%s) did not fail", data)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package sim
+
+import (
+	"image/color"
+	"math"
+)
+
+type ObstacleKind int
+
+const (
+	KindCactus ObstacleKind = iota
+	KindCactusCluster
+	KindRock
+	KindBird
+	KindLowBird
+	KindDivingBird
+)
+
+// ObstacleSpec describes one kind of hazard: when it starts showing up, how
+// often, and how forgiving its hit box is.
+type ObstacleSpec struct {
+	Name string
+	// minScore is the score from which the kind can spawn, and chance the
+	// percentage of its group's spawns it then takes.
+	minScore int
+	chance   int
+	// margin shrinks the hit box on every side, sprites have empty corners
+	margin float64
+}
+
+var ObstacleKinds = [...]ObstacleSpec{
+	KindCactus:        {Name: "cactus", margin: obstacleMargin},
+	KindCactusCluster: {Name: "cactus cluster", minScore: 800, chance: 20, margin: 10},
+	KindRock:          {Name: "rolling rock", minScore: 1200, chance: 20, margin: 6},
+	KindBird:          {Name: "bird", margin: obstacleMargin},
+	KindLowBird:       {Name: "low bird", minScore: 500, chance: 25, margin: obstacleMargin},
+	KindDivingBird:    {Name: "diving bird", minScore: 1500, chance: 20, margin: obstacleMargin},
+}
+
+// The obstacles spawn in two groups sharing a timer each. The first kind of
+// a group is the default, taking whatever chance the others leave.
+var (
+	groundKinds = []ObstacleKind{KindCactus, KindCactusCluster, KindRock}
+	flyingKinds = []ObstacleKind{KindBird, KindLowBird, KindDivingBird}
+)
+
+const (
+	// cactus clusters are made of the narrow frames only, so they stay
+	// within reach of a double jump
+	minClusterParts = 2
+	maxClusterParts = 3
+
+	rockSize = 40
+	// a rock rolls a bit faster than the ground, surging and slowing down
+	rockBaseSpeed  = 1.5
+	rockSpeedSwing = 1.5
+	rockSurgeTicks = 30
+
+	// a low bird flies with its belly this high above the ground, where a
+	// standing dino hits it and a ducking one does not
+	minLowBirdLift = 45
+	maxLowBirdLift = 60
+
+	// a diving bird drops to the height of a low bird once it gets this close
+	diveRange = 320
+	diveSpeed = 4
+
+	// birdWobble is how fast birds wobble, in radians per tick
+	birdWobble = 0.05
+)
+
+// clusterFrames are the cactus frames clusters are composed of.
+var clusterFrames = []int{0, 2}
+
+// cactus is a single cactus frame, or a cluster of them side by side.
+type cactus struct {
+	box
+	kind  ObstacleKind
+	frame int
+	// parts are the cactus frames of a cluster
+	parts   []int
+	cleared bool
+}
+
+func (c *cactus) Update(r *Runner, dt, speed float64) {
+	c.x -= speed * dt
+	r.passHazard(&c.cleared, c.box, c.kind)
+}
+
+func (c *cactus) Bounds() box {
+	// the widest cactus frame is mostly air between its stems
+	if c.kind == KindCactus && c.w > 100 {
+		return c.inset(40)
+	}
+	return c.inset(ObstacleKinds[c.kind].margin)
+}
+
+func (c *cactus) OnHit(r *Runner) bool {
+	return r.hitHazard(c.kind)
+}
+
+func (c *cactus) Draw(cv Canvas) {
+	if c.kind == KindCactus {
+		cv.Sprite(cactusRects[c.frame], c.x, c.y)
+		return
+	}
+	x := c.x
+	for _, frame := range c.parts {
+		cv.Sprite(cactusRects[frame], x, c.y+c.h-float64(cactusRects[frame].Dy()))
+		x += float64(cactusRects[frame].Dx())
+	}
+}
+
+// rock rolls along the ground a bit faster than the course, surging and
+// slowing down.
+type rock struct {
+	box
+	age     float64
+	cleared bool
+}
+
+func (k *rock) Update(r *Runner, dt, speed float64) {
+	k.age += dt
+	surge := math.Sin(k.age / rockSurgeTicks)
+	k.x -= (speed + rockBaseSpeed + surge*rockSpeedSwing) * dt
+	r.passHazard(&k.cleared, k.box, KindRock)
+}
+
+func (k *rock) Bounds() box {
+	return k.inset(ObstacleKinds[KindRock].margin)
+}
+
+func (k *rock) OnHit(r *Runner) bool {
+	return r.hitHazard(KindRock)
+}
+
+// Draw draws the rock as a disc with a notch turning as it rolls, the
+// sprite sheet has none.
+func (k *rock) Draw(cv Canvas) {
+	r := k.w / 2
+	cx, cy := k.x+r, k.y+r
+	cv.Disc(cx, cy, r, SpriteGray)
+	angle := -k.age / 8
+	cv.Disc(cx+math.Cos(angle)*r*0.6, cy+math.Sin(angle)*r*0.6, r/5, color.White)
+}
+
+// bird wobbles along, flies low, or dives at the dino, depending on its
+// kind.
+type bird struct {
+	box
+	kind    ObstacleKind
+	frame   int
+	age     float64
+	cleared bool
+}
+
+func (b *bird) Update(r *Runner, dt, speed float64) {
+	b.age += dt
+	osc := math.Sin(b.age * birdWobble)
+	b.x -= (speed + osc*1.5) * dt
+	switch b.kind {
+	case KindBird:
+		b.y += osc * 0.5 * dt
+	case KindDivingBird:
+		lowest := float64(ScreenHeight-GroundHeight) - b.h - maxLowBirdLift
+		if b.x-r.PlayerX < diveRange && b.y < lowest {
+			b.y = min(b.y+diveSpeed*dt, lowest)
+		}
+	}
+	// a low bird does not bob, it has to stay in its band
+	r.passHazard(&b.cleared, b.box, b.kind)
+}
+
+func (b *bird) Bounds() box {
+	return b.inset(ObstacleKinds[b.kind].margin)
+}
+
+func (b *bird) OnHit(r *Runner) bool {
+	return r.hitHazard(b.kind)
+}
+
+func (b *bird) Draw(cv Canvas) {
+	// flapping is cosmetic, both frames share the same size
+	frame := (b.frame + int(b.age)/10) % len(birdRects)
+	cv.Sprite(birdRects[frame], b.x, b.y)
+}
+
+// pickKind rolls which kind of the group kinds spawns next.
+func (r *Runner) pickKind(kinds []ObstacleKind) ObstacleKind {
+	roll := r.rng.Intn(100)
+	for _, k := range kinds[1:] {
+		spec := ObstacleKinds[k]
+		if r.Score < spec.minScore {
+			continue
+		}
+		if roll < spec.chance {
+			return k
+		}
+		roll -= spec.chance
+	}
+	return kinds[0]
+}
+
+// spawnGround puts a new hazard of the ground group on the course, maybe
+// with coins arcing over it.
+func (r *Runner) spawnGround() {
+	kind := r.pickKind(groundKinds)
+	groundY := float64(ScreenHeight - GroundHeight)
+	var b box
+	switch kind {
+	case KindCactusCluster:
+		c := &cactus{box: box{x: float64(ScreenWidth)}, kind: kind}
+		n := minClusterParts + r.rng.Intn(maxClusterParts-minClusterParts+1)
+		for range n {
+			frame := clusterFrames[r.rng.Intn(len(clusterFrames))]
+			c.parts = append(c.parts, frame)
+			c.w += float64(cactusRects[frame].Dx())
+			c.h = max(c.h, float64(cactusRects[frame].Dy()))
+		}
+		c.y = groundY - c.h
+		r.Entities = append(r.Entities, c)
+		b = c.box
+	case KindRock:
+		k := &rock{box: box{x: float64(ScreenWidth), y: groundY - rockSize, w: rockSize, h: rockSize}}
+		r.Entities = append(r.Entities, k)
+		b = k.box
+	default:
+		frame := r.rng.Intn(len(cactusRects))
+		w, h := float64(cactusRects[frame].Dx()), float64(cactusRects[frame].Dy())
+		c := &cactus{
+			box:   box{x: float64(ScreenWidth), y: groundY - h, w: w, h: h},
+			kind:  kind,
+			frame: frame,
+		}
+		r.Entities = append(r.Entities, c)
+		b = c.box
+	}
+	r.spawnCoinArc(b)
+}
+
+// spawnFlyer puts a new bird of the flying group on the course, maybe with
+// coins under it.
+func (r *Runner) spawnFlyer() {
+	kind := r.pickKind(flyingKinds)
+	frame := r.rng.Intn(len(birdRects))
+	w, h := float64(birdRects[frame].Dx()), float64(birdRects[frame].Dy())
+	b := &bird{box: box{x: float64(ScreenWidth), w: w, h: h}, kind: kind, frame: frame}
+
+	if kind == KindLowBird {
+		lift := float64(minLowBirdLift + r.rng.Intn(maxLowBirdLift-minLowBirdLift))
+		b.y = float64(ScreenHeight-GroundHeight) - h - lift
+		r.Entities = append(r.Entities, b)
+		return
+	}
+
+	minOffset := r.birdOffsetFloor()
+	randOffset := float64(r.rng.Intn(maxBirdOffset-minOffset)) + float64(minOffset)
+	b.y = float64(ScreenHeight - GroundHeight - DinoRunningHeight - randOffset)
+	r.Entities = append(r.Entities, b)
+	if kind == KindBird {
+		r.spawnCoinRow(b.box)
+	}
+}
+
+// birdOffsetFloor is how low the next bird may fly: high enough above a
+// tall cactus to leave room for jumping over it.
+func (r *Runner) birdOffsetFloor() int {
+	for i := len(r.Entities) - 1; i >= 0; i-- {
+		switch e := r.Entities[i].(type) {
+		case *cactus:
+			if e.h >= 100 {
+				return 160
+			}
+			return minBirdOffset
+		case *rock:
+			return minBirdOffset
+		}
+	}
+	return minBirdOffset
+}
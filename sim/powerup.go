@@ -0,0 +1,179 @@
+package sim
+
+import "fmt"
+
+type PowerUpKind int
+
+const (
+	PowerShield PowerUpKind = iota
+	PowerSlowMo
+	PowerMagnet
+	PowerExtraJump
+	PowerDash
+	NumPowerUps
+)
+
+const (
+	pickupSize = 30
+
+	minPickupInterval = 600
+	maxPickupInterval = 900
+
+	slowMoFactor = 0.6
+	dashFactor   = 1.6
+
+	// the magnet pulls pickups and coins in range toward the dino
+	magnetRange = 250
+	magnetPull  = 12
+)
+
+// PowerUp describes one kind of power-up. Timed ones last duration ticks
+// from the pickup, the others stack up to the charges the shield rules
+// allow, used up one at a time.
+type PowerUp struct {
+	Name     string // in the HUD
+	glyph    string // on the pickup
+	banner   string // blinks on screen when picked up
+	duration int
+}
+
+var PowerUps = [NumPowerUps]PowerUp{
+	PowerShield: {
+		Name:   "Shield",
+		glyph:  "S",
+		banner: "SHIELD IS READY",
+	},
+	PowerSlowMo: {
+		Name:     "Slow-mo",
+		glyph:    "~",
+		banner:   "SLOW MOTION",
+		duration: 5 * 60,
+	},
+	PowerMagnet: {
+		Name:     "Magnet",
+		glyph:    "M",
+		banner:   "MAGNET",
+		duration: 10 * 60,
+	},
+	PowerExtraJump: {
+		Name:     "Triple jump",
+		glyph:    "J",
+		banner:   "TRIPLE JUMP",
+		duration: 10 * 60,
+	},
+	PowerDash: {
+		Name:     "Dash",
+		glyph:    ">",
+		banner:   "INVINCIBLE DASH",
+		duration: 2 * 60,
+	},
+}
+
+// powerState is what a runner holds of one power-up: the ticks left of a
+// timed one, or the charges of the others.
+type powerState struct {
+	ticks   int
+	charges int
+}
+
+// Has reports whether the power-up kind is in effect.
+func (r *Runner) Has(kind PowerUpKind) bool {
+	s := r.powers[kind]
+	return s.charges > 0 || s.ticks > 0
+}
+
+// grant gives the runner the power-up kind, resetting the time of a timed
+// one or adding a charge, and announces it.
+func (r *Runner) grant(kind PowerUpKind) {
+	pu := PowerUps[kind]
+	if pu.duration > 0 {
+		r.powers[kind].ticks = pu.duration
+	} else {
+		r.powers[kind].charges = min(r.powers[kind].charges+1, r.ShieldRules.MaxCharges)
+	}
+	if kind == PowerShield {
+		r.emit(EventShieldGained, r.powers[kind].charges)
+	} else {
+		r.emit(EventPowerUp, int(kind))
+	}
+}
+
+// consume uses up a charge of the power-up kind.
+func (r *Runner) consume(kind PowerUpKind) {
+	r.powers[kind].charges = max(r.powers[kind].charges-1, 0)
+}
+
+// expirePowers counts the timed power-ups down by one tick.
+func (r *Runner) expirePowers() {
+	for i := range r.powers {
+		if r.powers[i].ticks > 0 {
+			r.powers[i].ticks--
+		}
+	}
+}
+
+// spawnPickup now and then puts a power-up on the course, within reach of
+// a jump.
+func (r *Runner) spawnPickup() {
+	r.pickupSpawnTick++
+	if r.pickupSpawnTick < r.pickupInterval {
+		return
+	}
+	r.pickupSpawnTick = 0
+	r.pickupInterval = minPickupInterval + r.rng.Intn(maxPickupInterval-minPickupInterval)
+
+	kind := PowerUpKind(r.rng.Intn(int(NumPowerUps)))
+	lift := float64(r.rng.Intn(120))
+	r.Entities = append(r.Entities, &pickup{
+		box: box{
+			x: float64(ScreenWidth),
+			y: float64(ScreenHeight-GroundHeight-pickupSize) - 20 - lift,
+			w: pickupSize,
+			h: pickupSize,
+		},
+		kind: kind,
+	})
+}
+
+// pickup floats along the course and grants its power-up to the dino
+// touching it.
+type pickup struct {
+	box
+	kind PowerUpKind
+}
+
+func (pk *pickup) Update(r *Runner, dt, speed float64) {
+	pk.x -= speed * dt
+	r.magnetize(&pk.box)
+}
+
+func (pk *pickup) Bounds() box {
+	return pk.box
+}
+
+func (pk *pickup) OnHit(r *Runner) bool {
+	r.grant(pk.kind)
+	return true
+}
+
+// PowerHUD lists the power-ups in effect, as shown in the HUD.
+func (r *Runner) PowerHUD() []string {
+	var lines []string
+	for kind, pu := range PowerUps {
+		s := r.powers[kind]
+		switch {
+		case s.ticks > 0:
+			lines = append(lines, fmt.Sprintf("%s: %.1fs", pu.Name, float64(s.ticks)/60))
+		case s.charges > 0:
+			lines = append(lines, fmt.Sprintf("%s: %d/%d", pu.Name, s.charges, r.ShieldRules.MaxCharges))
+		}
+	}
+	return lines
+}
+
+// Draw draws the pickup as a ring around the glyph of its power-up.
+func (pk *pickup) Draw(cv Canvas) {
+	r := pk.w / 2
+	cv.Ring(pk.x+r, pk.y+r, r-1, 2, SpriteGray)
+	cv.Glyph(PowerUps[pk.kind].glyph, pk.x+r, pk.y+r, SpriteGray)
+}
@@ -0,0 +1,126 @@
+package sim
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// ReplayVersion goes up whenever the simulation changes, since old
+	// replays no longer play out the same.
+	ReplayVersion = 6
+
+	// maxReplayTicks bounds the runs accepted for verification to an hour.
+	maxReplayTicks = 60 * 60 * 60
+)
+
+// Replay is everything needed to play a run again: the course seed and the
+// player's controls on every tick. Inputs are run-length encoded as
+// [bits, ticks] pairs, bit 0 being jump, bit 1 duck and bit 2 shield.
+// Shield is left out when the run was played by the default shield rules.
+type Replay struct {
+	Version int          `json:"version"`
+	Seed    int64        `json:"seed"`
+	Shield  *ShieldRules `json:"shield,omitempty"`
+	Inputs  [][2]int     `json:"inputs"`
+	Score   int          `json:"score"`
+	Coins   int          `json:"coins"`
+}
+
+// NewReplay starts the replay of a run on the course of seed, played by the
+// shield rules.
+func NewReplay(seed int64, rules ShieldRules) *Replay {
+	r := &Replay{Version: ReplayVersion, Seed: seed}
+	if rules != DefaultShieldRules {
+		r.Shield = &rules
+	}
+	return r
+}
+
+// ShieldRules returns the shield rules the run was played by.
+func (r *Replay) ShieldRules() ShieldRules {
+	if r.Shield == nil {
+		return DefaultShieldRules
+	}
+	return *r.Shield
+}
+
+// NewRunner starts a runner on the course of the replay, by its rules.
+func (r *Replay) NewRunner() *Runner {
+	p := NewRunner(r.Seed)
+	p.ShieldRules = r.ShieldRules()
+	return p
+}
+
+func InputBits(in Input) int {
+	bits := 0
+	if in.Jump {
+		bits |= 1
+	}
+	if in.Duck {
+		bits |= 2
+	}
+	if in.Shield {
+		bits |= 4
+	}
+	return bits
+}
+
+func BitsInput(bits int) Input {
+	return Input{Jump: bits&1 != 0, Duck: bits&2 != 0, Shield: bits&4 != 0}
+}
+
+// Record appends the controls of one more tick.
+func (r *Replay) Record(in Input) {
+	bits := InputBits(in)
+	if n := len(r.Inputs); n > 0 && r.Inputs[n-1][0] == bits {
+		r.Inputs[n-1][1]++
+		return
+	}
+	r.Inputs = append(r.Inputs, [2]int{bits, 1})
+}
+
+// Verify re-simulates the replay and checks it is a complete run that ends
+// in a death with the score and coins it claims.
+func (r *Replay) Verify() error {
+	if r.Version != ReplayVersion {
+		return fmt.Errorf("unsupported replay version %d", r.Version)
+	}
+	if err := r.ShieldRules().Check(); err != nil {
+		return err
+	}
+	ticks := 0
+	for _, span := range r.Inputs {
+		if span[0] < 0 || span[0] > 7 || span[1] <= 0 {
+			return errors.New("malformed inputs")
+		}
+		// checked on every span, a sum of huge spans could wrap around
+		if span[1] > maxReplayTicks-ticks {
+			return errors.New("replay too long")
+		}
+		ticks += span[1]
+	}
+
+	p := r.NewRunner()
+	tick := 0
+	for _, span := range r.Inputs {
+		in := BitsInput(span[0])
+		for range span[1] {
+			if p.Dead {
+				return fmt.Errorf("inputs continue after the dino died at tick %d", tick)
+			}
+			p.Update(in)
+			tick++
+		}
+	}
+	if !p.Dead {
+		return errors.New("the run does not end in a death")
+	}
+	if p.Score != r.Score {
+		return fmt.Errorf("claimed score %d, replay scores %d", r.Score, p.Score)
+	}
+	if p.CoinCount != r.Coins {
+		return fmt.Errorf("claimed %d coins, replay collects %d", r.Coins, p.CoinCount)
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package sim
+
+import (
+	"math"
+	"testing"
+)
+
+func TestVerifyRejectsLongReplays(t *testing.T) {
+	tests := [][][2]int{
+		{{0, maxReplayTicks + 1}},
+		{{0, maxReplayTicks}, {1, 1}},
+		// the spans add up to a small number once wrapped around
+		{{0, math.MaxInt}, {1, math.MaxInt}, {0, 2}},
+	}
+	for _, inputs := range tests {
+		r := &Replay{Version: ReplayVersion, Inputs: inputs}
+		if err := r.Verify(); err == nil || err.Error() != "replay too long" {
+			t.Errorf("Verify(%v) = %v, want replay too long", inputs, err)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package sim
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Input is the state of one player's controls during a single tick.
+type Input struct {
+	Jump bool
+	Duck bool
+	// Shield raises a shield by hand, if the dino holds one.
+	Shield bool
+}
+
+// Runner is a single dino running its own copy of a seeded course. Runners
+// sharing a seed and fed the same inputs go through exactly the same run.
+type Runner struct {
+	rng *rand.Rand
+	// Clouds drift by in the sky, see cloudLayer.
+	Clouds *Layer
+
+	PlayerX      float64
+	PlayerY      float64
+	VY           float64
+	JumpCount    int
+	OnGround     bool
+	IsDucking    bool
+	DuckDuration float64
+
+	Entities        []Entity
+	cactusSpawnTick int
+	birdSpawnTick   int
+	GroundX         float64
+
+	AnimFrame int
+	AnimTick  int
+
+	pickupSpawnTick int
+	pickupInterval  int
+	powers          [NumPowerUps]powerState
+	shieldsAwarded  int
+	// ShieldRules is the shield economy of the run, set before its first
+	// tick
+	ShieldRules  ShieldRules
+	Invulnerable int
+	CoinCount    int
+
+	// Score goes up by one every tick the dino runs, so it is also the
+	// time of the run in ticks
+	Score      int
+	SpeedLevel int
+	Dead       bool
+	// Finished tells a scripted course is over, the finish line crossed
+	Finished bool
+	// Course is the script of the course, nil for a seeded one
+	Course *CourseRun
+	// KilledBy is the kind of hazard that ended the run
+	KilledBy ObstacleKind
+	// Distance is how far the dino ran, in pixels
+	Distance float64
+
+	// Banners are drawn over the course, set from its events
+	Banners Banners
+
+	lastJumpKeyPressed   bool
+	lastDuckKeyPressed   bool
+	lastShieldKeyPressed bool
+
+	// events holds what happened on the last tick
+	events []Event
+}
+
+func NewRunner(seed int64) *Runner {
+	r := &Runner{
+		rng:      rand.New(rand.NewSource(seed)),                        // #nosec G404 -- course generation, not security
+		Clouds:   NewLayer(&cloudLayer, rand.New(rand.NewSource(seed))), // #nosec G404
+		PlayerX:  100,
+		PlayerY:  float64(ScreenHeight - GroundHeight - DinoRunningHeight),
+		OnGround: true,
+
+		pickupInterval: maxPickupInterval,
+		ShieldRules:    DefaultShieldRules,
+	}
+	return r
+}
+
+// Speed is how fast the course goes by, power-ups included.
+func (r *Runner) Speed() float64 {
+	speed := gameSpeedForScore(r.Score)
+	if r.Course != nil {
+		speed = r.Course.speed
+	}
+	if r.Has(PowerSlowMo) {
+		speed *= slowMoFactor
+	}
+	if r.Has(PowerDash) {
+		speed *= dashFactor
+	}
+	return speed
+}
+
+// maxJumps is how many jumps the dino can chain before landing.
+func (r *Runner) maxJumps() int {
+	if r.Has(PowerExtraJump) {
+		return maxjumpCount + 1
+	}
+	return maxjumpCount
+}
+
+// DinoBox returns the dino's current hit box, which shrinks while ducking.
+func (r *Runner) DinoBox() (x, y, w, h float64) {
+	if r.IsDucking {
+		return r.PlayerX, r.PlayerY + DuckYOffset, dinoDuckingWidth, dinoDuckingHeight
+	}
+	return r.PlayerX, r.PlayerY, dinoRunningWidth, DinoRunningHeight
+}
+
+// AnimateDead cycles the dead dino frames once the run is over.
+func (r *Runner) AnimateDead() {
+	r.AnimTick++
+	if r.AnimTick >= 10 {
+		r.AnimTick = 0
+		r.AnimFrame = (r.AnimFrame + 1) % len(DinoDeadRects)
+	}
+}
+
+// Update advances the run by one tick using in as the player's controls.
+func (r *Runner) Update(in Input) {
+	r.events = r.events[:0]
+	if r.Dead {
+		r.AnimateDead()
+		return
+	}
+	if r.Finished {
+		return
+	}
+
+	r.AnimTick++
+	r.expirePowers()
+	if r.Invulnerable > 0 {
+		r.Invulnerable--
+	}
+	currentSpeed := r.Speed()
+	// a scripted course sets its speed itself
+	if r.Course == nil {
+		r.levelUp()
+	}
+
+	// Jump
+	if in.Jump && !r.lastJumpKeyPressed && r.JumpCount < r.maxJumps() {
+		r.OnGround = false
+
+		if r.JumpCount == 0 {
+			r.VY = -10
+			r.emit(EventJumped, 0)
+		} else {
+			r.VY = -9
+			r.emit(EventDoubleJumped, 0)
+		}
+		r.JumpCount++
+	}
+	r.lastJumpKeyPressed = in.Jump
+
+	r.VY += 0.5
+	r.PlayerY += r.VY
+	groundY := float64(ScreenHeight - GroundHeight - DinoRunningHeight)
+	if r.PlayerY >= groundY {
+		if !r.OnGround {
+			r.emit(EventLanded, 0)
+		}
+		r.PlayerY = groundY
+		r.VY = 0
+		r.OnGround = true
+		r.JumpCount = 0
+	}
+
+	wasDucking := r.IsDucking
+	if in.Duck && r.lastDuckKeyPressed {
+		r.DuckDuration += 1.0 / 60.0
+		if r.DuckDuration <= maxDuckDuration {
+			r.IsDucking = true
+		} else {
+			r.IsDucking = false
+		}
+	} else {
+		r.IsDucking = false
+		r.DuckDuration = 0
+	}
+	r.lastDuckKeyPressed = in.Duck
+	switch {
+	case r.IsDucking && !wasDucking:
+		r.emit(EventDuckStarted, 0)
+	case wasDucking && in.Duck && !r.IsDucking:
+		r.emit(EventDuckTimedOut, 0)
+	}
+
+	if in.Shield && !r.lastShieldKeyPressed {
+		r.raiseShield()
+	}
+	r.lastShieldKeyPressed = in.Shield
+
+	r.Clouds.Update(currentSpeed)
+	// a scripted course puts everything on the course itself, shields
+	// included
+	if r.Course == nil {
+		r.spawnObstacles()
+		r.spawnPickup()
+	}
+
+	r.Score++
+	if r.Score%milestoneEvery == 0 {
+		r.emit(EventMilestone, r.Score)
+	}
+
+	if r.Course == nil {
+		r.awardShields()
+	}
+
+	// move ground
+	r.GroundX += currentSpeed
+	r.Distance += currentSpeed
+	groundW := GroundRect.Dx()
+	r.GroundX = math.Mod(r.GroundX, float64(groundW))
+
+	finished := r.Course != nil && r.runCourse()
+
+	// runners always step a whole tick, runs would not replay the same
+	// otherwise
+	r.updateEntities(1, currentSpeed)
+
+	if r.Dead {
+		r.emit(EventDied, r.Score)
+		r.AnimFrame = 0
+		r.AnimTick = 0
+		return
+	}
+	if finished {
+		r.Finished = true
+		r.emit(EventFinished, r.Score)
+		return
+	}
+
+	if r.AnimTick >= 10 {
+		r.AnimTick = 0
+		r.AnimFrame = (r.AnimFrame + 1) % len(DinoRunningRects)
+
+		if r.OnGround {
+			r.emit(EventStride, 0)
+		}
+	}
+}
+
+// spawnObstacles puts the hazards of a seeded course on it, each group on
+// its own timer.
+func (r *Runner) spawnObstacles() {
+	// cactus, and whatever else runs on the ground
+	r.cactusSpawnTick++
+	if r.cactusSpawnTick >= r.rng.Intn(100)+150 {
+		r.cactusSpawnTick = 0
+		r.spawnGround()
+	}
+
+	// birds
+	r.birdSpawnTick++
+	if r.birdSpawnTick >= r.rng.Intn(100)+r.rng.Intn(50)+200 {
+		r.birdSpawnTick = 0
+		r.spawnFlyer()
+	}
+}
+
+// levelUp moves the speed level up with the score.
+func (r *Runner) levelUp() {
+	speedStep := r.Score / gameSpeedScoreStep
+	maxSpeedStep := int((maxGameSpeed - BaseGameSpeed) / gameSpeedStep)
+	if speedStep > maxSpeedStep {
+		speedStep = maxSpeedStep
+	}
+	if speedStep > r.SpeedLevel {
+		r.SpeedLevel = speedStep
+		if speedStep > 0 {
+			r.emit(EventSpeedLevelUp, speedStep)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package sim
+
+import "errors"
+
+// ShieldRules is the shield economy. It is part of the simulation: a run
+// only plays out the same under the same rules, so replays carry the rules
+// they were played by when these are not the default ones.
+type ShieldRules struct {
+	// FirstAward is the score earning the first shield, then one more every
+	// AwardEvery points.
+	FirstAward int `json:"first_award"`
+	AwardEvery int `json:"award_every"`
+	// MaxCharges is how many shields a dino can hold at once. Awards and
+	// pickups past it are lost.
+	MaxCharges int `json:"max_charges"`
+	// IFrames is how long the dino stays invulnerable after a shield breaks,
+	// so the obstacle right behind does not kill it anyway.
+	IFrames int `json:"iframes"`
+	// RaisedTicks is how long a shield raised by hand protects the dino.
+	RaisedTicks int `json:"raised_ticks"`
+}
+
+// DefaultShieldRules are the rules of every run unless the player changes
+// them. Ranked runs are always played by these.
+var DefaultShieldRules = ShieldRules{
+	FirstAward:  1100,
+	AwardEvery:  1000,
+	MaxCharges:  3,
+	IFrames:     60,
+	RaisedTicks: 90,
+}
+
+// Check reports rules no run can be played by.
+func (s ShieldRules) Check() error {
+	if s.FirstAward < 0 || s.AwardEvery <= 0 || s.MaxCharges < 0 || s.IFrames < 0 || s.RaisedTicks < 0 {
+		return errors.New("shield rules need a positive award_every and no negative values")
+	}
+	return nil
+}
+
+// shieldAwards is how many shields a run has earned by score.
+func (s ShieldRules) shieldAwards(score int) int {
+	if score < s.FirstAward {
+		return 0
+	}
+	return (score-s.FirstAward)/s.AwardEvery + 1
+}
+
+// awardShields grants the shields earned by the current score. Every award
+// counts once, whether or not there is room for another charge.
+func (r *Runner) awardShields() {
+	if n := r.ShieldRules.shieldAwards(r.Score); n > r.shieldsAwarded {
+		r.shieldsAwarded = n
+		r.grant(PowerShield)
+	}
+}
+
+// raiseShield spends a charge on purpose for a moment of invulnerability.
+func (r *Runner) raiseShield() {
+	if !r.Has(PowerShield) || r.Invulnerable > 0 {
+		return
+	}
+	r.consume(PowerShield)
+	r.Invulnerable = r.ShieldRules.RaisedTicks
+	r.emit(EventShieldRaised, 0)
+}
+
+// breakShield spends a charge on an obstacle the dino hit, reporting false
+// when there was none left.
+func (r *Runner) breakShield() bool {
+	if !r.Has(PowerShield) {
+		return false
+	}
+	r.consume(PowerShield)
+	r.Invulnerable = r.ShieldRules.IFrames
+	r.emit(EventShieldBroken, 0)
+	return true
+}
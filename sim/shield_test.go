@@ -0,0 +1,115 @@
+package sim
+
+import "testing"
+
+func TestShieldAwards(t *testing.T) {
+	tests := []struct {
+		score int
+		want  int
+	}{
+		{0, 0},
+		{1099, 0},
+		{1100, 1},
+		{2099, 1},
+		{2100, 2},
+		{5100, 5},
+	}
+	for _, tt := range tests {
+		if got := DefaultShieldRules.shieldAwards(tt.score); got != tt.want {
+			t.Errorf("shieldAwards(%d) = %d, want %d", tt.score, got, tt.want)
+		}
+	}
+}
+
+// playTo runs r with no input and a clear course up to score.
+func playTo(r *Runner, score int) {
+	for r.Score < score {
+		r.Entities = nil
+		r.Update(Input{})
+	}
+}
+
+func TestShieldsStack(t *testing.T) {
+	r := NewRunner(1)
+	playTo(r, 1100)
+	if got := r.powers[PowerShield].charges; got != 1 {
+		t.Fatalf("charges at 1100 = %d, want 1", got)
+	}
+	// holding a shield no longer swallows the next award
+	playTo(r, 2100)
+	if got := r.powers[PowerShield].charges; got != 2 {
+		t.Fatalf("charges at 2100 = %d, want 2", got)
+	}
+	rules := DefaultShieldRules
+	playTo(r, 1100+rules.AwardEvery*(rules.MaxCharges+2))
+	if got := r.powers[PowerShield].charges; got != rules.MaxCharges {
+		t.Fatalf("charges = %d, want the cap of %d", got, rules.MaxCharges)
+	}
+}
+
+func TestCustomShieldRules(t *testing.T) {
+	rules := ShieldRules{FirstAward: 100, AwardEvery: 100, MaxCharges: 1, IFrames: 10, RaisedTicks: 20}
+	rep := NewReplay(1, rules)
+	if rep.Shield == nil {
+		t.Fatal("the replay does not carry custom rules")
+	}
+	r := rep.NewRunner()
+	playTo(r, 500)
+	if got := r.powers[PowerShield].charges; got != 1 {
+		t.Fatalf("charges at 500 = %d, want the cap of 1", got)
+	}
+	r.Update(Input{Shield: true})
+	if r.Invulnerable != rules.RaisedTicks {
+		t.Fatalf("raised shield lasts %d ticks, want %d", r.Invulnerable, rules.RaisedTicks)
+	}
+
+	if NewReplay(1, DefaultShieldRules).Shield != nil {
+		t.Error("the replay carries the default rules")
+	}
+}
+
+func TestShieldBreakIframes(t *testing.T) {
+	r := NewRunner(1)
+	r.grant(PowerShield)
+	// a cactus right on the dino, and one more each time it is needed
+	cactusAt := func() []Entity {
+		return []Entity{&cactus{box: box{x: r.PlayerX, y: float64(ScreenHeight - GroundHeight - 70), w: 34, h: 70}}}
+	}
+
+	r.Entities = cactusAt()
+	r.Update(Input{})
+	if r.Dead || r.Has(PowerShield) {
+		t.Fatalf("the shield should have taken the hit: dead %v, shield %v", r.Dead, r.Has(PowerShield))
+	}
+	// a second obstacle right behind hits during the invulnerability frames
+	r.Entities = cactusAt()
+	r.Update(Input{})
+	if r.Dead {
+		t.Fatal("died during the invulnerability frames")
+	}
+
+	for r.Invulnerable > 0 {
+		r.Entities = nil
+		r.Update(Input{})
+	}
+	r.Entities = cactusAt()
+	r.Update(Input{})
+	if !r.Dead {
+		t.Fatal("survived a hit without a shield")
+	}
+}
+
+func TestRaiseShield(t *testing.T) {
+	r := NewRunner(1)
+	r.Update(Input{Shield: true})
+	if r.Invulnerable > 0 {
+		t.Fatal("raised a shield without any charge")
+	}
+
+	r.Update(Input{})
+	r.grant(PowerShield)
+	r.Update(Input{Shield: true})
+	if r.Has(PowerShield) || r.Invulnerable == 0 {
+		t.Fatalf("raising should spend the charge: shield %v, invulnerable %d", r.Has(PowerShield), r.Invulnerable)
+	}
+}
@@ -0,0 +1,62 @@
+// Package sim is the simulation of a run: the dino, everything coming
+// along the course and the rules they play by, scripted courses and
+// replays. It draws nothing and plays nothing, so it runs anywhere, with or
+// without a display, as in the terminal game and the leaderboard server.
+package sim
+
+const (
+	maxjumpCount    = 2
+	maxDuckDuration = 3.0 // 3s for 60 FPS
+
+	ScreenWidth  = 800
+	ScreenHeight = 600
+
+	dinoRunningWidth  = 88
+	DinoRunningHeight = 94
+	dinoDuckingWidth  = 118
+	dinoDuckingHeight = 60
+
+	dinoMargin     = float64(20)
+	obstacleMargin = float64(5)
+
+	minBirdOffset = 100
+	maxBirdOffset = 180
+
+	DuckYOffset = 34
+
+	GroundHeight = 100
+
+	BaseGameSpeed      = 5.0
+	maxGameSpeed       = 10.0
+	gameSpeedStep      = 0.5
+	gameSpeedScoreStep = 500
+
+	// PixelsPerMetre turns distances on the course into metres: the dino,
+	// 88 pixels long, is about a metre.
+	PixelsPerMetre = 88
+)
+
+func isColliding(ax, ay, aw, ah, am float64, bx, by, bw, bh, bm float64) bool {
+	ax += am
+	ay += am
+	aw -= 2 * am
+	ah -= 2 * am
+
+	bx += bm
+	by += bm
+	bw -= 2 * bm
+	bh -= 2 * bm
+
+	return ax < bx+bw &&
+		ax+aw > bx &&
+		ay < by+bh &&
+		ay+ah > by
+}
+
+func gameSpeedForScore(score int) float64 {
+	speed := BaseGameSpeed + float64(score/gameSpeedScoreStep)*gameSpeedStep
+	if speed > maxGameSpeed {
+		return maxGameSpeed
+	}
+	return speed
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/colorm"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+
+	"github.com/yongtenglei/dino/sim"
+)
+
+// skin is a look for the dino, bought with coins.
+type skin struct {
+	id    string
+	name  string
+	price int
+	// tint replaces the gray of the dino sprites.
+	tint color.RGBA
+}
+
+var skins = []skin{
+	{id: "classic", name: "Classic", tint: sim.SpriteGray},
+	{id: "moss", name: "Moss", price: 50, tint: color.RGBA{0x3d, 0x7a, 0x3a, 0xff}},
+	{id: "ocean", name: "Ocean", price: 100, tint: color.RGBA{0x2b, 0x5d, 0x9c, 0xff}},
+	{id: "ember", name: "Ember", price: 200, tint: color.RGBA{0xb8, 0x3a, 0x1e, 0xff}},
+	{id: "royal", name: "Royal", price: 350, tint: color.RGBA{0x6a, 0x2c, 0x91, 0xff}},
+	{id: "gold", name: "Gold", price: 500, tint: color.RGBA{0xc9, 0x9a, 0x1b, 0xff}},
+}
+
+// defaultSkin is worn by the players whose skin is not known: the second
+// player of versus, other players of a race and broadcasters.
+var defaultSkin = skins[0]
+
+func findSkin(id string) (skin, bool) {
+	for _, s := range skins {
+		if s.id == id {
+			return s, true
+		}
+	}
+	return skin{}, false
+}
+
+// colorM maps the sprite gray to the tint of the skin and keeps white white.
+func (s skin) colorM() colorm.ColorM {
+	var cm colorm.ColorM
+	scale := func(tint uint8) float64 {
+		return float64(0xff-int(tint)) / float64(0xff-int(sim.SpriteGray.R))
+	}
+	r, g, b := scale(s.tint.R), scale(s.tint.G), scale(s.tint.B)
+	cm.Scale(r, g, b, 1)
+	cm.Translate(1-r, 1-g, 1-b, 0)
+	return cm
+}
+
+// skin is the skin the player wears.
+func (g *Game) skin() skin {
+	s, _ := findSkin(g.wallet.Skin)
+	return s
+}
+
+// bank adds the coins of a finished run to the wallet.
+func (g *Game) bank(coins int) {
+	if coins == 0 {
+		return
+	}
+	g.wallet.Deposit(coins)
+	if err := g.wallet.Save(); err != nil {
+		log.Printf("saving wallet: %v", err)
+	}
+}
+
+// updateSkins runs the skin shop: LEFT/RIGHT browse, ENTER buys or puts on
+// and ESC goes back to the start screen.
+func (g *Game) updateSkins() {
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyEscape):
+		g.menu = menuNone
+		g.skinStatus = ""
+	case inpututil.IsKeyJustPressed(ebiten.KeyLeft) || inpututil.IsKeyJustPressed(ebiten.KeyUp):
+		g.skinCursor = (g.skinCursor + len(skins) - 1) % len(skins)
+		g.skinStatus = ""
+	case inpututil.IsKeyJustPressed(ebiten.KeyRight) || inpututil.IsKeyJustPressed(ebiten.KeyDown):
+		g.skinCursor = (g.skinCursor + 1) % len(skins)
+		g.skinStatus = ""
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnter):
+		s := skins[g.skinCursor]
+		if !buy(g.wallet, s) {
+			g.skinStatus = fmt.Sprintf("%d more coins needed", s.price-g.wallet.Coins)
+			return
+		}
+		g.skinStatus = "Wearing " + s.name
+		if err := g.wallet.Save(); err != nil {
+			log.Printf("saving wallet: %v", err)
+		}
+	}
+}
+
+func (g *Game) drawSkins(screen *ebiten.Image) {
+	screen.Fill(color.RGBA{0x30, 0x30, 0x40, 0xff})
+
+	s := skins[g.skinCursor]
+	op := &colorm.DrawImageOptions{}
+	op.GeoM.Translate(100, float64(sim.ScreenHeight-sim.GroundHeight-sim.DinoRunningHeight))
+	colorm.DrawImage(screen, g.sprites.dinoRunning[g.animFrame%len(g.sprites.dinoRunning)], s.colorM(), op)
+
+	drawCentered(screen, "SKINS", 100, color.White)
+	drawCentered(screen, fmt.Sprintf("Coins: %d", g.wallet.Coins), 130, color.White)
+	for i, sk := range skins {
+		line := fmt.Sprintf("%-8s %4d coins", sk.name, sk.price)
+		switch {
+		case sk.id == g.wallet.Skin:
+			line = fmt.Sprintf("%-8s    wearing", sk.name)
+		case owns(g.wallet, sk.id):
+			line = fmt.Sprintf("%-8s      owned", sk.name)
+		}
+		if i == g.skinCursor {
+			line = "> " + line + " <"
+		}
+		drawCentered(screen, line, float64(180+i*20), color.White)
+	}
+	drawCentered(screen, g.skinStatus, float64(200+len(skins)*20), color.White)
+	drawCentered(screen, "LEFT/RIGHT: Browse | ENTER: Buy or wear | ESC: Back", float64(240+len(skins)*20), gray)
+}
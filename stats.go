@@ -0,0 +1,247 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image/color"
+	"io"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	text "github.com/hajimehoshi/ebiten/v2/text/v2"
+
+	"github.com/yongtenglei/dino/sim"
+	"github.com/yongtenglei/dino/storage"
+)
+
+const (
+	statsFile = "stats.json"
+
+	// histogramBucket is the score range of a bar of the score histogram,
+	// the last bar takes every score above
+	histogramBucket = 500
+	histogramBars   = 12
+
+	maxSessions = 10
+)
+
+// lifetimeStats add up every run of a profile.
+type lifetimeStats struct {
+	Runs          int     `json:"runs"`
+	Metres        float64 `json:"metres"`
+	TotalScore    int     `json:"total_score"`
+	Jumps         int     `json:"jumps"`
+	DoubleJumps   int     `json:"double_jumps"`
+	Ducks         int     `json:"ducks"`
+	BirdsDodged   int     `json:"birds_dodged"`
+	CactiCleared  int     `json:"cacti_cleared"`
+	RocksDodged   int     `json:"rocks_dodged"`
+	ShieldsEarned int     `json:"shields_earned"`
+	ShieldsUsed   int     `json:"shields_used"`
+	// Deaths counts the runs ended by each kind of hazard, by name.
+	Deaths map[string]int `json:"deaths"`
+	// Histogram counts runs by score, histogramBucket points a bar.
+	Histogram []int `json:"histogram"`
+	// Sessions are the latest launches of the game a run was played in.
+	Sessions []session `json:"sessions"`
+}
+
+// session is one launch of the game.
+type session struct {
+	Started    string `json:"started"`
+	Runs       int    `json:"runs"`
+	TotalScore int    `json:"total_score"`
+}
+
+func (s session) average() int {
+	if s.Runs == 0 {
+		return 0
+	}
+	return s.TotalScore / s.Runs
+}
+
+func loadStats() *lifetimeStats {
+	s := &lifetimeStats{}
+	if err := storage.LoadJSON(statsFile, s); err != nil {
+		log.Printf("loading stats: %v", err)
+	}
+	if s.Deaths == nil {
+		s.Deaths = map[string]int{}
+	}
+	if len(s.Histogram) != histogramBars {
+		bars := make([]int, histogramBars)
+		copy(bars, s.Histogram)
+		s.Histogram = bars
+	}
+	return s
+}
+
+func (s *lifetimeStats) save() error {
+	return storage.SaveJSON(statsFile, s)
+}
+
+// statsTracker adds the events of player one's runs to the stats of the
+// profile, saving them at the end of every run.
+type statsTracker struct {
+	lifetime *lifetimeStats
+	// sessionStarted tells whether this launch already has its session
+	sessionStarted bool
+}
+
+func (t *statsTracker) onEvent(p *sim.Runner, e sim.Event) {
+	s := t.lifetime
+	switch e.Kind {
+	case sim.EventJumped:
+		s.Jumps++
+	case sim.EventDoubleJumped:
+		s.DoubleJumps++
+	case sim.EventDuckStarted:
+		s.Ducks++
+	case sim.EventCleared:
+		switch sim.ObstacleKind(e.Value) {
+		case sim.KindCactus, sim.KindCactusCluster:
+			s.CactiCleared++
+		case sim.KindRock:
+			s.RocksDodged++
+		default:
+			s.BirdsDodged++
+		}
+	case sim.EventShieldGained:
+		s.ShieldsEarned++
+	case sim.EventShieldBroken, sim.EventShieldRaised:
+		s.ShieldsUsed++
+	case sim.EventDied, sim.EventFinished:
+		t.endRun(p)
+	}
+}
+
+// endRun counts the run of p, over now by a death or at the finish line.
+func (t *statsTracker) endRun(p *sim.Runner) {
+	s := t.lifetime
+	s.Runs++
+	s.Metres += p.Distance / sim.PixelsPerMetre
+	s.TotalScore += p.Score
+	if p.Dead {
+		s.Deaths[sim.ObstacleKinds[p.KilledBy].Name]++
+	}
+	s.Histogram[min(p.Score/histogramBucket, histogramBars-1)]++
+
+	if !t.sessionStarted {
+		t.sessionStarted = true
+		s.Sessions = append(s.Sessions, session{Started: time.Now().Format(time.DateTime)})
+		if n := len(s.Sessions); n > maxSessions {
+			s.Sessions = slices.Delete(s.Sessions, 0, n-maxSessions)
+		}
+	}
+	last := &s.Sessions[len(s.Sessions)-1]
+	last.Runs++
+	last.TotalScore += p.Score
+
+	if err := s.save(); err != nil {
+		log.Printf("saving stats: %v", err)
+	}
+}
+
+// lines lays the stats out as text, for the stats screen and the terminal.
+func (s *lifetimeStats) lines() []string {
+	average := 0
+	if s.Runs > 0 {
+		average = s.TotalScore / s.Runs
+	}
+	lines := []string{
+		fmt.Sprintf("Runs %d   Distance %.1f km   Average score %d", s.Runs, s.Metres/1000, average),
+		fmt.Sprintf("Jumps %d   Double jumps %d   Ducks %d", s.Jumps, s.DoubleJumps, s.Ducks),
+		fmt.Sprintf("Cacti cleared %d   Birds dodged %d   Rocks dodged %d", s.CactiCleared, s.BirdsDodged, s.RocksDodged),
+		fmt.Sprintf("Shields earned %d, used %d", s.ShieldsEarned, s.ShieldsUsed),
+		"",
+		"Killed by",
+	}
+	for _, spec := range sim.ObstacleKinds {
+		if n := s.Deaths[spec.Name]; n > 0 {
+			lines = append(lines, fmt.Sprintf("  %-15s %4d", spec.Name, n))
+		}
+	}
+
+	lines = append(lines, "", "Scores")
+	most := max(slices.Max(s.Histogram), 1)
+	for i, n := range s.Histogram {
+		label := fmt.Sprintf("%5d-%-5d", i*histogramBucket, (i+1)*histogramBucket-1)
+		if i == histogramBars-1 {
+			label = fmt.Sprintf("%5d+     ", i*histogramBucket)
+		}
+		bar := strings.Repeat("#", (n*30+most-1)/most)
+		lines = append(lines, fmt.Sprintf("  %s %-30s %d", label, bar, n))
+	}
+
+	lines = append(lines, "", "Average score by session")
+	for _, ss := range slices.Backward(s.Sessions) {
+		lines = append(lines, fmt.Sprintf("  %s  %3d runs  %6d", ss.Started, ss.Runs, ss.average()))
+	}
+	return lines
+}
+
+// updateStats runs the stats screen: UP/DOWN scroll and ESC goes back to
+// the start screen.
+func (g *Game) updateStats() {
+	n := len(g.stats.lifetime.lines())
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyEscape):
+		g.menu = menuNone
+	case inpututil.IsKeyJustPressed(ebiten.KeyUp):
+		g.statsScroll = max(g.statsScroll-1, 0)
+	case inpututil.IsKeyJustPressed(ebiten.KeyDown):
+		g.statsScroll = min(g.statsScroll+1, max(n-statsRows, 0))
+	}
+}
+
+// statsRows is how many lines of stats fit on the screen.
+const statsRows = 22
+
+func (g *Game) drawStats(screen *ebiten.Image) {
+	screen.Fill(color.RGBA{0x30, 0x30, 0x40, 0xff})
+
+	drawCentered(screen, "STATS - "+storage.CurrentProfile(), 40, color.White)
+	lines := g.stats.lifetime.lines()
+	end := min(g.statsScroll+statsRows, len(lines))
+	for i, line := range lines[g.statsScroll:end] {
+		op := &text.DrawOptions{}
+		op.GeoM.Translate(120, float64(80+i*20))
+		op.ColorScale.ScaleWithColor(color.White)
+		text.Draw(screen, line, face, op)
+	}
+	drawCentered(screen, "UP/DOWN: Scroll | ESC: Back", float64(sim.ScreenHeight-40), gray)
+}
+
+// statsCommand implements `dino stats`, printing the stats of a profile,
+// as JSON with --json.
+func statsCommand(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print the stats as JSON")
+	profile := fs.String("profile", "", "profile to print the stats of, the current one by default")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *profile != "" {
+		// only for this command, the current profile stays what it is
+		if err := storage.PeekProfile(*profile); err != nil {
+			return err
+		}
+	}
+	s := loadStats()
+	if *asJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(s)
+	}
+	for _, line := range s.lines() {
+		if _, err := fmt.Fprintln(out, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"log"
+	"time"
+
+	"github.com/yongtenglei/dino/sim"
+)
+
+const historyFile = "history.json"
+
+// Attempt is one daily challenge run.
+type Attempt struct {
+	Score  int  `json:"score"`
+	Ranked bool `json:"ranked"`
+}
+
+// DayRecord is every daily challenge run of a day.
+type DayRecord struct {
+	Attempts []Attempt `json:"attempts"`
+}
+
+// History is the local record of daily challenge runs, keyed by day, and
+// of the best score of all.
+type History struct {
+	Days map[string]*DayRecord `json:"days"`
+	Best int                   `json:"best"`
+}
+
+// LoadHistory loads the history of the current profile.
+func LoadHistory() *History {
+	h := &History{}
+	if err := LoadJSON(historyFile, h); err != nil {
+		log.Printf("loading score history: %v", err)
+	}
+	if h.Days == nil {
+		h.Days = map[string]*DayRecord{}
+	}
+	return h
+}
+
+func (h *History) Save() error {
+	return SaveJSON(historyFile, h)
+}
+
+// HasRanked reports whether the ranked attempt for day is already used up.
+func (h *History) HasRanked(day string) bool {
+	rec, ok := h.Days[day]
+	if !ok {
+		return false
+	}
+	for _, a := range rec.Attempts {
+		if a.Ranked {
+			return true
+		}
+	}
+	return false
+}
+
+// StartRanked uses up the ranked attempt of day as its run starts, scoring
+// 0 until the run ends: quitting halfway gives no second try.
+func (h *History) StartRanked(day string) {
+	h.Record(day, 0, true)
+}
+
+// Record adds a run scoring score to day. The ranked run of the day gets
+// the attempt StartRanked set aside.
+func (h *History) Record(day string, score int, ranked bool) {
+	rec, ok := h.Days[day]
+	if !ok {
+		rec = &DayRecord{}
+		h.Days[day] = rec
+	}
+	if ranked {
+		for i := range rec.Attempts {
+			if rec.Attempts[i].Ranked {
+				rec.Attempts[i].Score = score
+				return
+			}
+		}
+	}
+	rec.Attempts = append(rec.Attempts, Attempt{Score: score, Ranked: ranked})
+}
+
+// BestOf returns the best score of day, and the score of its ranked attempt
+// (-1 if the day has no ranked attempt).
+func (h *History) BestOf(day string) (best, ranked int) {
+	ranked = -1
+	rec, ok := h.Days[day]
+	if !ok {
+		return 0, ranked
+	}
+	for _, a := range rec.Attempts {
+		best = max(best, a.Score)
+		if a.Ranked {
+			ranked = a.Score
+		}
+	}
+	return best, ranked
+}
+
+// Streak counts the consecutive days played, ending on day.
+func (h *History) Streak(day string) int {
+	last, err := time.Parse(sim.DayLayout, day)
+	if err != nil {
+		return 0
+	}
+	n := 0
+	for d := last; ; d = d.AddDate(0, 0, -1) {
+		rec, ok := h.Days[sim.DayKey(d)]
+		if !ok || len(rec.Attempts) == 0 {
+			return n
+		}
+		n++
+	}
+}
@@ -0,0 +1,40 @@
+package storage
+
+import "testing"
+
+func TestRankedAttemptUsedAtStart(t *testing.T) {
+	h := &History{Days: map[string]*DayRecord{}}
+	h.StartRanked("2026-03-02")
+	if !h.HasRanked("2026-03-02") {
+		t.Fatal("the ranked attempt is still there once its run started")
+	}
+	h.Record("2026-03-02", 420, true)
+	h.Record("2026-03-02", 500, false)
+	if best, ranked := h.BestOf("2026-03-02"); best != 500 || ranked != 420 {
+		t.Errorf("BestOf = %d, %d, want 500, 420", best, ranked)
+	}
+	if n := len(h.Days["2026-03-02"].Attempts); n != 2 {
+		t.Errorf("%d attempts, want 2", n)
+	}
+}
+
+func TestStreak(t *testing.T) {
+	h := &History{Days: map[string]*DayRecord{}}
+	for _, day := range []string{"2026-02-27", "2026-02-28", "2026-03-01", "2026-03-03"} {
+		h.Record(day, 100, false)
+	}
+	tests := []struct {
+		day  string
+		want int
+	}{
+		{"2026-03-01", 3},
+		{"2026-03-02", 0},
+		{"2026-03-03", 1},
+		{"bad", 0},
+	}
+	for _, tt := range tests {
+		if got := h.Streak(tt.day); got != tt.want {
+			t.Errorf("Streak(%s) = %d, want %d", tt.day, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,365 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/yongtenglei/dino/sim"
+)
+
+// Everything the game saves belongs to a profile: settings, wallet, score
+// history, achievements and replays. Each profile is a directory under
+// profiles/ in the data dir, and profiles.json remembers the one in use.
+
+const (
+	profilesDir    = "profiles"
+	profilesFile   = "profiles.json"
+	defaultProfile = "default"
+	// MaxProfileName is the longest a profile name may be
+	MaxProfileName = 16
+
+	// ReplaysDir is the folder of a profile saved replays go in
+	ReplaysDir = "replays"
+	// ProfileExt is the extension of exported profiles
+	ProfileExt = ".dinoprofile"
+)
+
+// profileFiles are the files moved into the default profile from the data
+// dir, where they lived before there were profiles.
+var profileFiles = []string{"settings.json", historyFile, walletFile, "achievements.json"}
+
+// currentProfile is the profile LoadJSON and SaveJSON work with, set on
+// first use.
+var currentProfile string
+
+// CurrentProfile returns the name of the profile in use.
+func CurrentProfile() string {
+	_, _ = profilesRoot()
+	return currentProfile
+}
+
+type profileIndex struct {
+	Current string `json:"current"`
+}
+
+// profilesRoot returns the directory holding the profiles, opening them on
+// first use.
+func profilesRoot() (string, error) {
+	if currentProfile == "" {
+		if err := openProfiles(); err != nil {
+			return "", err
+		}
+	}
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, profilesDir), nil
+}
+
+// profileDir returns the directory of the current profile, creating it on
+// first use.
+func profileDir() (string, error) {
+	root, err := profilesRoot()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(root, currentProfile)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// openProfiles picks the profile used last, moving the saves of a game
+// from before profiles into the default one.
+func openProfiles() error {
+	base, err := DataDir()
+	if err != nil {
+		return err
+	}
+	root := filepath.Join(base, profilesDir)
+	if _, err := os.Stat(root); errors.Is(err, fs.ErrNotExist) {
+		dir := filepath.Join(root, defaultProfile)
+		if err := os.MkdirAll(dir, 0o750); err != nil {
+			return err
+		}
+		for _, name := range profileFiles {
+			err := os.Rename(filepath.Join(base, name), filepath.Join(dir, name))
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+		}
+	}
+
+	var idx profileIndex
+	if err := ReadJSON(filepath.Join(base, profilesFile), &idx); err != nil {
+		return err
+	}
+	currentProfile = defaultProfile
+	if ValidProfileName(idx.Current) == nil {
+		if info, err := os.Stat(filepath.Join(root, idx.Current)); err == nil && info.IsDir() {
+			currentProfile = idx.Current
+		}
+	}
+	return nil
+}
+
+func saveProfileIndex() error {
+	base, err := DataDir()
+	if err != nil {
+		return err
+	}
+	return WriteJSON(filepath.Join(base, profilesFile), profileIndex{Current: currentProfile})
+}
+
+func ValidProfileName(name string) error {
+	if n := utf8.RuneCountInString(name); n == 0 || n > MaxProfileName {
+		return fmt.Errorf("profile names are 1 to %d characters long", MaxProfileName)
+	}
+	if strings.TrimSpace(name) != name {
+		return errors.New("profile names do not start or end with a space")
+	}
+	for _, c := range name {
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) && !strings.ContainsRune(" -_", c) {
+			return errors.New("profile names take letters, digits, spaces, - and _")
+		}
+	}
+	return nil
+}
+
+func ProfileExists(name string) bool {
+	if ValidProfileName(name) != nil {
+		return false
+	}
+	root, err := profilesRoot()
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(filepath.Join(root, name))
+	return err == nil && info.IsDir()
+}
+
+// ListProfiles returns the names of every profile, sorted.
+func ListProfiles() ([]string, error) {
+	root, err := profilesRoot()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() && ValidProfileName(e.Name()) == nil {
+			names = append(names, e.Name())
+		}
+	}
+	return names, nil
+}
+
+func CreateProfile(name string) error {
+	if err := ValidProfileName(name); err != nil {
+		return err
+	}
+	if ProfileExists(name) {
+		return fmt.Errorf("profile %q already exists", name)
+	}
+	root, err := profilesRoot()
+	if err != nil {
+		return err
+	}
+	return os.MkdirAll(filepath.Join(root, name), 0o750)
+}
+
+func RenameProfile(old, name string) error {
+	if !ProfileExists(old) {
+		return fmt.Errorf("no profile %q", old)
+	}
+	if err := ValidProfileName(name); err != nil {
+		return err
+	}
+	if ProfileExists(name) {
+		return fmt.Errorf("profile %q already exists", name)
+	}
+	root, err := profilesRoot()
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(filepath.Join(root, old), filepath.Join(root, name)); err != nil {
+		return err
+	}
+	if old == currentProfile {
+		currentProfile = name
+		return saveProfileIndex()
+	}
+	return nil
+}
+
+// DeleteProfile removes a profile and everything in it, except the one in
+// use.
+func DeleteProfile(name string) error {
+	if !ProfileExists(name) {
+		return fmt.Errorf("no profile %q", name)
+	}
+	if name == currentProfile {
+		return errors.New("the profile in use cannot be deleted")
+	}
+	root, err := profilesRoot()
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(filepath.Join(root, name))
+}
+
+// UseProfile makes name the current profile, now and on the next launch.
+func UseProfile(name string) error {
+	if !ProfileExists(name) {
+		return fmt.Errorf("no profile %q", name)
+	}
+	currentProfile = name
+	return saveProfileIndex()
+}
+
+// PlayAs makes name the current profile, now and on the next launch,
+// creating it if new.
+func PlayAs(name string) error {
+	if !ProfileExists(name) {
+		if err := CreateProfile(name); err != nil {
+			return err
+		}
+	}
+	return UseProfile(name)
+}
+
+// PeekProfile makes name the current profile for this process only; the
+// next launch still opens the one used last.
+func PeekProfile(name string) error {
+	if !ProfileExists(name) {
+		return fmt.Errorf("no profile %q", name)
+	}
+	currentProfile = name
+	return nil
+}
+
+// SaveReplay keeps the replay r as name in the replays folder of the
+// current profile.
+func SaveReplay(name string, r *sim.Replay) error {
+	dir, err := profileDir()
+	if err != nil {
+		return err
+	}
+	dir = filepath.Join(dir, ReplaysDir)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return err
+	}
+	return WriteJSON(filepath.Join(dir, name+".json"), r)
+}
+
+// profileBundle is a whole profile in a single file, to export and import.
+type profileBundle struct {
+	Name string `json:"name"`
+	// Files holds every file of the profile by its slash separated path.
+	Files map[string]json.RawMessage `json:"files"`
+}
+
+// ExportProfile writes the profile name to w as a single file, see
+// ImportProfile.
+func ExportProfile(name string, w io.Writer) error {
+	if !ProfileExists(name) {
+		return fmt.Errorf("no profile %q", name)
+	}
+	root, err := profilesRoot()
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(root, name)
+	b := profileBundle{Name: name, Files: map[string]json.RawMessage{}}
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(p) != ".json" {
+			return err
+		}
+		data, err := os.ReadFile(p) // #nosec G304 -- a file of the profile
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		b.Files[filepath.ToSlash(rel)] = data
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(b)
+}
+
+// bundlePath reports whether rel is fit to be a file of an imported
+// profile: a JSON file inside of it on every OS. Backslashes and colons are
+// turned away too, they would name other places on Windows.
+func bundlePath(rel string) bool {
+	return fs.ValidPath(rel) && filepath.IsLocal(filepath.FromSlash(rel)) &&
+		!strings.ContainsAny(rel, `\:`) && path.Ext(rel) == ".json"
+}
+
+// ImportProfile adds the profile exported to r as name, or under its own
+// name when name is empty, and returns the name it got.
+func ImportProfile(r io.Reader, name string) (string, error) {
+	var b profileBundle
+	if err := json.NewDecoder(r).Decode(&b); err != nil {
+		return "", fmt.Errorf("reading profile: %w", err)
+	}
+	if name == "" {
+		name = b.Name
+	}
+	for rel := range b.Files {
+		if !bundlePath(rel) {
+			return "", fmt.Errorf("unexpected file %q in profile", rel)
+		}
+	}
+	if err := CreateProfile(name); err != nil {
+		return "", err
+	}
+	root, err := profilesRoot()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(root, name)
+	for rel, data := range b.Files {
+		p := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0o750); err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(p, data, 0o600); err != nil {
+			return "", err
+		}
+	}
+	return name, nil
+}
+
+// WriteProfileFile exports the profile name to file.
+func WriteProfileFile(name, file string) error {
+	f, err := os.Create(file) // #nosec G304 -- the file the user asked for
+	if err != nil {
+		return err
+	}
+	if err := ExportProfile(name, f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImportProfileRejectsPaths(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("AppData", t.TempDir())
+	bad := []string{
+		"../escape.json",
+		"/abs.json",
+		"replays/../../escape.json",
+		`replays\..\..\escape.json`,
+		"C:/escape.json",
+		"c:escape.json",
+		"settings.txt",
+		"",
+	}
+	for i, rel := range bad {
+		bundle := `{"name": "p", "files": {"` + strings.ReplaceAll(rel, `\`, `\\`) + `": {}}}`
+		if _, err := ImportProfile(strings.NewReader(bundle), "p"+string(rune('a'+i))); err == nil {
+			t.Errorf("imported a profile with the file %q", rel)
+		}
+	}
+
+	name, err := ImportProfile(strings.NewReader(`{"name": "good", "files": {"replays/best.json": {}}}`), "")
+	if err != nil || name != "good" {
+		t.Fatalf("ImportProfile = %q, %v, want good", name, err)
+	}
+}
+
+func TestUnreadableSaveMovedAside(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("AppData", t.TempDir())
+	dir, err := profileDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, walletFile)
+	if err := os.WriteFile(path, []byte(`{"coins": 12`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var w Wallet
+	if err := LoadJSON(walletFile, &w); err == nil {
+		t.Fatal("LoadJSON read a truncated file")
+	}
+	if data, err := os.ReadFile(path + ".bak"); err != nil || string(data) != `{"coins": 12` {
+		t.Errorf("the unreadable save was not kept aside: %q, %v", data, err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("the unreadable save is still in place: %v", err)
+	}
+}
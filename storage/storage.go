@@ -0,0 +1,110 @@
+// Package storage keeps the per-profile save files: settings, history,
+// wallet, replays and the player's own levels.
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const (
+	appDirName = "dino"
+	levelsDir  = "levels"
+)
+
+// DataDir returns the directory holding everything the game persists,
+// creating it on first use.
+func DataDir() (string, error) {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(base, appDirName)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// unreadable holds the save files that could not be read nor moved aside,
+// SaveJSON refuses to write over them.
+var unreadable = map[string]bool{}
+
+// LoadJSON decodes the named file of the current profile into v.
+// A missing file is not an error and leaves v untouched. A file that cannot
+// be read is moved aside to a .bak file, for the next save not to write
+// over what the player had.
+func LoadJSON(name string, v any) error {
+	dir, err := profileDir()
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, name)
+	err = ReadJSON(path, v)
+	if err == nil {
+		return nil
+	}
+	if mvErr := os.Rename(path, path+".bak"); mvErr != nil {
+		unreadable[path] = true
+		return fmt.Errorf("%s: %w, and it could not be moved aside: %w", path, err, mvErr)
+	}
+	return fmt.Errorf("%s: %w, moved it aside to %s.bak", path, err, name)
+}
+
+// SaveJSON writes v to the named file of the current profile.
+func SaveJSON(name string, v any) error {
+	dir, err := profileDir()
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, name)
+	if unreadable[path] {
+		return fmt.Errorf("not saving over %s, it could not be read", path)
+	}
+	return WriteJSON(path, v)
+}
+
+// ReadJSON decodes the file at path into v. A missing file is not an error
+// and leaves v untouched.
+func ReadJSON(path string, v any) error {
+	data, err := os.ReadFile(path) // #nosec G304 -- a file under the data dir
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// WriteJSON writes v to path, going through a temporary file so a crash
+// never leaves a truncated save behind.
+func WriteJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// LevelsDir returns the folder the player's own levels go in, creating it
+// on first use.
+func LevelsDir() (string, error) {
+	base, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(base, levelsDir)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
@@ -0,0 +1,32 @@
+package storage
+
+import "log"
+
+const walletFile = "wallet.json"
+
+// Wallet is the player's coin purse and the skins bought with it.
+type Wallet struct {
+	Coins int `json:"coins"`
+	// Earned counts every coin ever collected, spent or not.
+	Earned int      `json:"earned"`
+	Skins  []string `json:"skins"`
+	Skin   string   `json:"skin"`
+}
+
+// LoadWallet loads the wallet of the current profile.
+func LoadWallet() *Wallet {
+	w := &Wallet{}
+	if err := LoadJSON(walletFile, w); err != nil {
+		log.Printf("loading wallet: %v", err)
+	}
+	return w
+}
+
+func (w *Wallet) Save() error {
+	return SaveJSON(walletFile, w)
+}
+
+func (w *Wallet) Deposit(coins int) {
+	w.Coins += coins
+	w.Earned += coins
+}
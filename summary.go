@@ -0,0 +1,260 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"log"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+
+	"github.com/yongtenglei/dino/sim"
+	"github.com/yongtenglei/dino/storage"
+)
+
+const (
+	// summaryDelay is how long the summary ignores the keys, so a jump
+	// pressed just too late does not skip it.
+	summaryDelay = 45
+
+	// the thumbnail is cut out of the screen around the collision
+	thumbWidth  = 320
+	thumbHeight = 200
+	thumbScale  = 0.6
+)
+
+// summaryButton is one of the choices of the game-over summary.
+type summaryButton struct {
+	label string
+	key   ebiten.Key
+	do    func(g *Game)
+}
+
+var summaryButtons = []summaryButton{
+	{label: "S: Save", key: ebiten.KeyS, do: (*Game).saveRunReplay},
+	{label: "W: Watch", key: ebiten.KeyW, do: (*Game).watchRunReplay},
+	{label: "C: Same course", key: ebiten.KeyC, do: (*Game).retryCourse},
+	{label: "ESC: Menu", key: ebiten.KeyEscape, do: (*Game).backToMenu},
+	{label: "SPACE: New run", key: ebiten.KeySpace, do: func(g *Game) { g.startRun(g.mode, newSeed()) }},
+}
+
+// runTally counts what the summary tells of player one's run in progress.
+type runTally struct {
+	cleared     int
+	shieldsUsed int
+}
+
+func (t *runTally) onEvent(_ *sim.Runner, e sim.Event) {
+	switch e.Kind {
+	case sim.EventCleared:
+		t.cleared++
+	case sim.EventShieldBroken, sim.EventShieldRaised:
+		t.shieldsUsed++
+	}
+}
+
+// runSummary is the game-over screen of a single player run.
+type runSummary struct {
+	mode      gameMode
+	run       *sim.Runner
+	replay    *sim.Replay
+	tally     runTally
+	newRecord bool
+
+	// thumb is the screen around the collision, frozen on the first frame
+	// drawn after it
+	thumb  *ebiten.Image
+	ticks  int
+	cursor int
+	status string
+}
+
+// summarize opens the summary of player one's run, just over.
+func (g *Game) summarize(newRecord bool) {
+	if g.summary != nil && g.summary.thumb != nil {
+		g.summary.thumb.Deallocate()
+	}
+	g.summary = &runSummary{
+		mode:      g.mode,
+		run:       g.players[0],
+		replay:    g.replay,
+		tally:     g.tally,
+		newRecord: newRecord,
+		cursor:    len(summaryButtons) - 1,
+	}
+}
+
+// updateSummary runs the summary: LEFT/RIGHT pick a button and ENTER
+// presses it, or its key does, R being another way to start a new run.
+func (g *Game) updateSummary() {
+	s := g.summary
+	s.ticks++
+	if s.ticks < summaryDelay {
+		return
+	}
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyLeft):
+		s.cursor = (s.cursor + len(summaryButtons) - 1) % len(summaryButtons)
+	case inpututil.IsKeyJustPressed(ebiten.KeyRight):
+		s.cursor = (s.cursor + 1) % len(summaryButtons)
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnter):
+		summaryButtons[s.cursor].do(g)
+	case inpututil.IsKeyJustPressed(ebiten.KeyR):
+		g.startRun(g.mode, newSeed())
+	default:
+		for _, b := range summaryButtons {
+			if inpututil.IsKeyJustPressed(b.key) {
+				b.do(g)
+				return
+			}
+		}
+	}
+}
+
+func (g *Game) saveRunReplay() {
+	s := g.summary
+	name := fmt.Sprintf("%s-%d", time.Now().Format("2006-01-02_150405"), s.run.Score)
+	if err := storage.SaveReplay(name, s.replay); err != nil {
+		log.Printf("saving replay: %v", err)
+		s.status = "Could not save the replay"
+		return
+	}
+	s.status = fmt.Sprintf("Saved to %s/%s.json in your profile", storage.ReplaysDir, name)
+}
+
+// watchRunReplay plays the run again from its replay, coming back to the
+// summary at its end.
+func (g *Game) watchRunReplay() {
+	s := g.summary
+	g.playback = g.playback[:0]
+	for _, span := range s.replay.Inputs {
+		for range span[1] {
+			g.playback = append(g.playback, sim.BitsInput(span[0]))
+		}
+	}
+	g.players = append(g.players[:0], s.replay.NewRunner())
+	g.playbackLinger = summaryDelay
+	g.mode = modeReplay
+	g.gameOver = false
+}
+
+// updatePlayback advances the replay being watched by a tick.
+func (g *Game) updatePlayback() {
+	if g.fx.frozen() {
+		return
+	}
+	p := g.players[0]
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		g.endPlayback()
+		return
+	}
+	// the end of the replay stays on screen for a moment
+	if p.Dead || len(g.playback) == 0 {
+		p.AnimateDead()
+		g.playbackLinger--
+		if g.playbackLinger <= 0 {
+			g.endPlayback()
+		}
+		return
+	}
+	p.Update(g.playback[0])
+	g.observe(p)
+	g.playback = g.playback[1:]
+}
+
+func (g *Game) endPlayback() {
+	s := g.summary
+	g.mode = s.mode
+	g.players = append(g.players[:0], s.run)
+	g.gameOver = true
+	s.ticks = 0
+}
+
+// retryCourse runs the same course again. Daily runs always do, endless
+// ones get the seed of the run just over and stay off the leaderboard.
+func (g *Game) retryCourse() {
+	g.startRun(g.mode, g.summary.replay.Seed)
+	g.retried = g.mode == modeEndless
+}
+
+// backToMenu leaves the summary for the start screen.
+func (g *Game) backToMenu() {
+	g.startScreen = true
+	g.gameOver = false
+}
+
+// freezeThumb cuts the collision out of the frame drawn on screen.
+func (s *runSummary) freezeThumb(screen *ebiten.Image) {
+	groundY := sim.ScreenHeight - sim.GroundHeight
+	x := int(s.run.PlayerX) - 40
+	rect := image.Rect(x, groundY-thumbHeight+20, x+thumbWidth, groundY+20)
+	s.thumb = ebiten.NewImage(thumbWidth, thumbHeight)
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(-float64(rect.Min.X), -float64(rect.Min.Y))
+	s.thumb.DrawImage(screen.SubImage(rect).(*ebiten.Image), op)
+}
+
+func (g *Game) drawSummary(screen *ebiten.Image) {
+	s := g.summary
+	if s.thumb == nil {
+		s.freezeThumb(screen)
+	}
+	p := s.run
+	ink := g.night.ink()
+
+	panel := image.Rect(sim.ScreenWidth/2-260, 30, sim.ScreenWidth/2+260, 350)
+	bg := color.RGBA{0xff, 0xff, 0xff, 0xe0}
+	if g.night.amount >= 0.5 {
+		bg = color.RGBA{0x20, 0x20, 0x28, 0xe0}
+	}
+	screen.SubImage(panel).(*ebiten.Image).Fill(bg)
+
+	drawCentered(screen, "GAME OVER", 45, color.RGBA{0xff, 0x00, 0x00, 0xff})
+	scoreText := fmt.Sprintf("Score %d   Best %d", p.Score, g.history.Best)
+	if s.newRecord {
+		scoreText = fmt.Sprintf("Score %d   NEW RECORD!", p.Score)
+	}
+	drawCentered(screen, scoreText, 70, ink)
+	secs := p.Score / 60
+	drawCentered(screen, fmt.Sprintf("Time %d:%02d   Max speed level %d", secs/60, secs%60, p.SpeedLevel), 90, ink)
+	drawCentered(screen, fmt.Sprintf("Obstacles cleared %d   Shields used %d", s.tally.cleared, s.tally.shieldsUsed), 110, ink)
+	drawCentered(screen, "Killed by a "+sim.ObstacleKinds[p.KilledBy].Name, 130, ink)
+
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(thumbScale, thumbScale)
+	op.GeoM.Translate(float64(sim.ScreenWidth)/2-thumbWidth*thumbScale/2, 150)
+	screen.DrawImage(s.thumb, op)
+
+	if s.ticks < summaryDelay {
+		return
+	}
+	var buttons string
+	for i, b := range summaryButtons {
+		if i > 0 {
+			buttons += "  "
+		}
+		if i == s.cursor {
+			buttons += "[" + b.label + "]"
+		} else {
+			buttons += " " + b.label + " "
+		}
+	}
+	drawCentered(screen, buttons, 290, ink)
+	drawCentered(screen, "LEFT/RIGHT + ENTER, or the keys", 310, gray)
+	drawCentered(screen, s.status, 330, ink)
+}
+
+// drawSummaryExtras adds what the mode of the run has to tell below the
+// summary.
+func (g *Game) drawSummaryExtras(screen *ebiten.Image) {
+	if g.mode == modeDaily {
+		best, _ := g.history.BestOf(g.runDay)
+		streak := g.history.Streak(g.runDay)
+		drawCentered(screen, fmt.Sprintf("Today's best: %d | Streak: %d day(s)", best, streak), 370, g.night.ink())
+	}
+	if g.submitStatus != "" {
+		drawCentered(screen, g.submitStatus, 390, g.night.ink())
+	}
+}
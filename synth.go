@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// synthSound is a sound effect made from scratch: a parameter set the game
+// turns into PCM samples when it starts.
+type synthSound struct {
+	name     string
+	duration float64 // seconds
+	gain     float64
+
+	// pulseWidth and pulseGap, in samples, make clicks shaped by a Hamming
+	// window, one every pulseGap samples.
+	pulseWidth int
+	pulseGap   int
+
+	// notes play one after the other, sharing the duration. Each is a chord
+	// of sine waves fading out linearly after attack seconds.
+	notes  [][]float64
+	attack float64
+}
+
+// synthSounds are the sound effects the game synthesizes. footsteps and
+// shield are the ports of the scripts that once made assets/run.wav and
+// assets/shield.wav.
+var synthSounds = struct {
+	footsteps synthSound
+	shield    synthSound
+	coin      synthSound
+	powerUp   synthSound
+	speedUp   synthSound
+}{
+	footsteps: synthSound{name: "run", duration: 0.3, gain: 0.8, pulseWidth: 100, pulseGap: 15000},
+	shield:    synthSound{name: "shield", duration: 0.25, gain: 0.4, notes: [][]float64{{880, 1320}}},
+	coin: synthSound{
+		name: "coin", duration: 0.16, gain: 0.3, attack: 0.005,
+		notes: [][]float64{{988}, {1319}},
+	},
+	powerUp: synthSound{
+		name: "powerup", duration: 0.36, gain: 0.3, attack: 0.005,
+		notes: [][]float64{{523}, {659}, {784}, {1047}},
+	},
+	speedUp: synthSound{
+		name: "speedup", duration: 0.4, gain: 0.3, attack: 0.01,
+		notes: [][]float64{{440, 554, 659}, {587, 740, 880}},
+	},
+}
+
+// allSynthSounds lists the synthesized sounds, for gen-sfx.
+func allSynthSounds() []synthSound {
+	s := synthSounds
+	return []synthSound{s.footsteps, s.shield, s.coin, s.powerUp, s.speedUp}
+}
+
+// samples renders the sound at rate samples per second, from -1 to 1.
+func (s synthSound) samples(rate int) []float64 {
+	out := make([]float64, int(float64(rate)*s.duration))
+
+	if s.pulseGap > 0 {
+		for start := 0; start < len(out); start += s.pulseGap {
+			end := min(start+s.pulseWidth, len(out))
+			n := end - start
+			for i := range n {
+				hamming := 1.0
+				if n > 1 {
+					hamming = 0.54 - 0.46*math.Cos(2*math.Pi*float64(i)/float64(n-1))
+				}
+				out[start+i] += hamming
+			}
+		}
+	}
+
+	if len(s.notes) > 0 {
+		noteLen := len(out) / len(s.notes)
+		attack := int(s.attack * float64(rate))
+		for k, chord := range s.notes {
+			for i := range noteLen {
+				t := float64(i) / float64(rate)
+				env := 1 - float64(i)/float64(noteLen)
+				if i < attack {
+					env *= float64(i) / float64(attack)
+				}
+				var v float64
+				for _, f := range chord {
+					v += math.Sin(2 * math.Pi * f * t)
+				}
+				out[k*noteLen+i] += v / float64(len(chord)) * env
+			}
+		}
+	}
+
+	for i, v := range out {
+		out[i] = max(-1, min(1, v*s.gain))
+	}
+	return out
+}
+
+func pcm16(v float64) int16 {
+	return int16(v * math.MaxInt16)
+}
+
+// pcm renders the sound as 16-bit little-endian stereo, the format the
+// audio players take.
+func (s synthSound) pcm(rate int) []byte {
+	samples := s.samples(rate)
+	b := make([]byte, 0, len(samples)*4)
+	for _, v := range samples {
+		b = binary.LittleEndian.AppendUint16(b, uint16(pcm16(v)))
+		b = binary.LittleEndian.AppendUint16(b, uint16(pcm16(v)))
+	}
+	return b
+}
+
+// wav encodes the sound as a 16-bit mono WAV file.
+func (s synthSound) wav(rate int) []byte {
+	samples := s.samples(rate)
+	dataLen := uint32(len(samples) * 2) // #nosec G115 -- sounds last a fraction of a second
+	r := uint32(rate)                   // #nosec G115 -- a sample rate
+
+	le := binary.LittleEndian
+	b := []byte("RIFF")
+	b = le.AppendUint32(b, 36+dataLen)
+	b = append(b, "WAVEfmt "...)
+	b = le.AppendUint32(b, 16) // fmt chunk size
+	b = le.AppendUint16(b, 1)  // PCM
+	b = le.AppendUint16(b, 1)  // mono
+	b = le.AppendUint32(b, r)
+	b = le.AppendUint32(b, r*2) // bytes per second
+	b = le.AppendUint16(b, 2)   // bytes per frame
+	b = le.AppendUint16(b, 16)  // bits per sample
+	b = append(b, "data"...)
+	b = le.AppendUint32(b, dataLen)
+	for _, v := range samples {
+		b = le.AppendUint16(b, uint16(pcm16(v)))
+	}
+	return b
+}
+
+// genSFXCommand implements `dino gen-sfx`, writing every synthesized sound
+// to a WAV file to listen to or inspect.
+func genSFXCommand(args []string) error {
+	fs := flag.NewFlagSet("gen-sfx", flag.ExitOnError)
+	dir := fs.String("dir", "sfx", "directory to write the WAV files to")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(*dir, 0o750); err != nil {
+		return err
+	}
+	for _, s := range allSynthSounds() {
+		path := filepath.Join(*dir, s.name+".wav")
+		if err := os.WriteFile(path, s.wav(sampleRate), 0o600); err != nil {
+			return err
+		}
+		fmt.Println(path)
+	}
+	return nil
+}
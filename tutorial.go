@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/yongtenglei/dino/sim"
+	"github.com/yongtenglei/dino/storage"
+)
+
+const (
+	coursesFile = "courses.json"
+
+	// a prompt waiting for an event gives up after this long, in ticks
+	maxPromptTicks = 6 * 60
+	promptTicks    = 3 * 60
+)
+
+// courseRecord is what a profile did on one scripted course.
+type courseRecord struct {
+	Attempts  int `json:"attempts"`
+	Completed int `json:"completed"`
+	// Furthest is the furthest the dino got, in metres.
+	Furthest float64 `json:"furthest"`
+	// BestTicks and BestScore are the quickest finish and the best score of
+	// a finished run, see courseScore.
+	BestTicks int `json:"bestTicks,omitempty"`
+	BestScore int `json:"bestScore,omitempty"`
+}
+
+// courseRecords are the records of a profile by course name.
+type courseRecords map[string]*courseRecord
+
+func loadCourseRecords() courseRecords {
+	recs := courseRecords{}
+	if err := storage.LoadJSON(coursesFile, &recs); err != nil {
+		log.Printf("loading course records: %v", err)
+	}
+	return recs
+}
+
+func (recs courseRecords) save() error {
+	return storage.SaveJSON(coursesFile, recs)
+}
+
+// completed reports whether the course name was ever finished.
+func (recs courseRecords) completed(name string) bool {
+	rec := recs[name]
+	return rec != nil && rec.Completed > 0
+}
+
+// endCourse records the run p, just over, on the course being played, and
+// tells of the records it broke.
+func (g *Game) endCourse(p *sim.Runner) {
+	name := g.course.Name
+	rec := g.courses[name]
+	if rec == nil {
+		rec = &courseRecord{}
+		g.courses[name] = rec
+	}
+	rec.Attempts++
+	g.courseNews = ""
+	if p.Finished {
+		if rec.Completed > 0 && p.Score < rec.BestTicks {
+			g.courseNews = "New best time!"
+		}
+		if rec.Completed > 0 && sim.CourseScore(p) > rec.BestScore {
+			g.courseNews = strings.TrimSpace(g.courseNews + " New best score!")
+		}
+		if rec.Completed == 0 || p.Score < rec.BestTicks {
+			rec.BestTicks = p.Score
+		}
+		rec.BestScore = max(rec.BestScore, sim.CourseScore(p))
+		rec.Completed++
+	}
+	rec.Furthest = max(rec.Furthest, p.Distance/sim.PixelsPerMetre)
+	if err := g.courses.save(); err != nil {
+		log.Printf("saving course records: %v", err)
+	}
+}
+
+// coursePrompt is the prompt of a course script on screen, if any.
+type coursePrompt struct {
+	text  string
+	until sim.EventKind
+	slow  bool
+	ticks int
+}
+
+// tick counts the prompt down, it goes when its time is up.
+func (cp *coursePrompt) tick() {
+	if cp.ticks > 0 {
+		cp.ticks--
+	}
+}
+
+func (cp *coursePrompt) showing() bool {
+	return cp.ticks > 0
+}
+
+// slowed reports whether the game runs in slow motion for the prompt.
+func (cp *coursePrompt) slowed() bool {
+	return cp.showing() && cp.slow
+}
+
+// onCourseEvent puts up the prompts of the course of player one, and takes
+// them down once the player did what they ask.
+func (g *Game) onCourseEvent(p *sim.Runner, e sim.Event) {
+	if g.mode != modeCourse || !g.ownRun(p) {
+		return
+	}
+	cp := &g.prompt
+	switch {
+	case e.Kind == sim.EventPrompt:
+		s := &p.Course.Script.Steps[e.Value]
+		*cp = coursePrompt{text: g.promptText(s.Prompt), until: s.UntilEvent(), slow: s.Slow, ticks: promptTicks}
+		if cp.until >= 0 {
+			cp.ticks = maxPromptTicks
+		}
+	case e.Kind == cp.until || e.Kind == sim.EventDied || e.Kind == sim.EventFinished:
+		cp.ticks = 0
+	}
+}
+
+// promptText puts the keys of the player in the prompt s.
+func (g *Game) promptText(s string) string {
+	return strings.NewReplacer(
+		"{jump}", keyNames(g.settings.JumpKeys),
+		"{duck}", keyNames(g.settings.DuckKeys),
+		"{shield}", keyNames(g.settings.ShieldKeys),
+	).Replace(s)
+}
+
+// startCourse starts a run of the course script c.
+func (g *Game) startCourse(c *sim.CourseScript) {
+	g.course = c
+	g.startScreen = false
+	g.startRun(modeCourse, 0)
+}
+
+// updateCourseOver takes the keys of the end of a course: ESC goes back to
+// the start screen, or to the levels for a level.
+func (g *Game) updateCourseOver() bool {
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		g.startScreen = true
+		g.gameOver = false
+		if g.course != sim.Tutorial {
+			g.menu = menuLevels
+		}
+		return true
+	}
+	return false
+}
+
+// drawCourse draws the finish line and the prompt over the course of p.
+func (g *Game) drawCourse(screen *ebiten.Image, p *sim.Runner) {
+	ink := g.night.ink()
+	c := p.Course.Script
+	if x := p.PlayerX + c.Finish*sim.PixelsPerMetre - p.Distance; x < sim.ScreenWidth {
+		top := float32(sim.ScreenHeight - sim.GroundHeight - 160)
+		for y := top; y < sim.ScreenHeight-sim.GroundHeight+10; y += 16 {
+			vector.DrawFilledRect(screen, float32(x), y, 4, 8, ink, false)
+		}
+	}
+
+	label := fmt.Sprintf("%s %.0fm / %.0fm", strings.ToUpper(c.Name), min(p.Distance/sim.PixelsPerMetre, c.Finish), c.Finish)
+	drawCentered(screen, label, 20, ink)
+	if g.prompt.showing() && !g.gameOver {
+		drawCentered(screen, g.prompt.text, 160, ink)
+	}
+}
+
+func (g *Game) drawCourseOver(screen *ebiten.Image) {
+	p := g.players[0]
+	ink := g.night.ink()
+	if p.Finished {
+		drawCentered(screen, g.course.Name+" complete!", 60, ink)
+		drawCentered(screen, fmt.Sprintf("Time %.1fs   Score %d", float64(p.Score)/60, sim.CourseScore(p)), 90, ink)
+		drawCentered(screen, g.courseNews, 105, ink)
+	} else {
+		drawCentered(screen, "GAME OVER", 60, color.RGBA{0xff, 0x00, 0x00, 0xff})
+		drawCentered(screen, fmt.Sprintf("Made it %.0fm of %.0fm", p.Distance/sim.PixelsPerMetre, g.course.Finish), 90, ink)
+	}
+	drawCentered(screen, "SPACE or R: Again | ESC: Back", 125, ink)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/yongtenglei/dino/sim"
+)
+
+// controls maps a player's keys and, when connected, gamepad to an Input.
+type controls struct {
+	jumpKeys   []ebiten.Key
+	duckKeys   []ebiten.Key
+	shieldKeys []ebiten.Key
+	gamepad    int // index into the connected gamepads
+}
+
+var (
+	playerOneControls = controls{
+		jumpKeys:   []ebiten.Key{ebiten.KeySpace, ebiten.KeyK},
+		duckKeys:   []ebiten.Key{ebiten.KeyDown, ebiten.KeyJ},
+		shieldKeys: []ebiten.Key{ebiten.KeyX},
+		gamepad:    0,
+	}
+	playerTwoControls = controls{
+		jumpKeys:   []ebiten.Key{ebiten.KeyW},
+		duckKeys:   []ebiten.Key{ebiten.KeyS},
+		shieldKeys: []ebiten.Key{ebiten.KeyE},
+		gamepad:    1,
+	}
+)
+
+// playerOne is player one's controls, with the keys of the settings.
+func (g *Game) playerOne() controls {
+	c := playerOneControls
+	c.jumpKeys = g.settings.JumpKeys
+	c.duckKeys = g.settings.DuckKeys
+	c.shieldKeys = g.settings.ShieldKeys
+	return c
+}
+
+// keyNames spells keys out for the hints on screen, as in "SPACE/K".
+func keyNames(keys []ebiten.Key) string {
+	names := make([]string, len(keys))
+	for i, k := range keys {
+		names[i] = strings.ToUpper(strings.TrimPrefix(k.String(), "Arrow"))
+	}
+	return strings.Join(names, "/")
+}
+
+func anyKeyPressed(keys []ebiten.Key) bool {
+	for _, k := range keys {
+		if ebiten.IsKeyPressed(k) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c controls) read(gamepads []ebiten.GamepadID) sim.Input {
+	in := sim.Input{
+		Jump:   anyKeyPressed(c.jumpKeys),
+		Duck:   anyKeyPressed(c.duckKeys),
+		Shield: anyKeyPressed(c.shieldKeys),
+	}
+	if c.gamepad < len(gamepads) {
+		id := gamepads[c.gamepad]
+		if ebiten.IsStandardGamepadLayoutAvailable(id) {
+			in.Jump = in.Jump || ebiten.IsStandardGamepadButtonPressed(id, ebiten.StandardGamepadButtonRightBottom)
+			in.Duck = in.Duck || ebiten.IsStandardGamepadButtonPressed(id, ebiten.StandardGamepadButtonLeftBottom)
+			in.Shield = in.Shield || ebiten.IsStandardGamepadButtonPressed(id, ebiten.StandardGamepadButtonRightTop)
+		}
+	}
+	return in
+}
+
+const (
+	// versusCropY is where the visible part of a versus viewport starts. The
+	// sky above it never holds anything but clouds.
+	versusCropY  = 150
+	versusHeight = sim.ScreenHeight / 2
+	versusScale  = float64(versusHeight) / float64(sim.ScreenHeight-versusCropY)
+)
+
+// versusWinner returns the index of the last dino standing, or -1 on a draw.
+func versusWinner(players []*sim.Runner) int {
+	winner, best := -1, -1
+	for i, p := range players {
+		switch {
+		case p.Score > best:
+			winner, best = i, p.Score
+		case p.Score == best:
+			winner = -1
+		}
+	}
+	return winner
+}
+
+// drawVersus draws every player in its own horizontal stripe of the screen,
+// player one on top.
+func (g *Game) drawVersus(screen *ebiten.Image) {
+	if g.viewport == nil {
+		g.viewport = ebiten.NewImage(sim.ScreenWidth, sim.ScreenHeight)
+	}
+	visibleW := float64(sim.ScreenWidth) * versusScale
+	for i, p := range g.players {
+		g.viewport.Fill(color.White)
+		world := g.night.begin(g.viewport)
+		sk := defaultSkin
+		if i == 0 {
+			sk = g.skin()
+		}
+		g.drawWorld(world, p, sk)
+		g.night.end(g.viewport)
+		g.drawBanners(g.viewport, p)
+
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Scale(versusScale, versusScale)
+		op.GeoM.Translate((float64(sim.ScreenWidth)-visibleW)/2, float64(i*versusHeight))
+		crop := g.viewport.SubImage(image.Rect(0, versusCropY, sim.ScreenWidth, sim.ScreenHeight)).(*ebiten.Image)
+		screen.DrawImage(crop, op)
+
+		g.drawHUD(screen, p, 10, float64(i*versusHeight+20), i+1)
+	}
+
+	vector.DrawFilledRect(screen, 0, versusHeight-1, sim.ScreenWidth, 2, g.night.ink(), false)
+}
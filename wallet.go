@@ -0,0 +1,38 @@
+package main
+
+import (
+	"slices"
+
+	"github.com/yongtenglei/dino/storage"
+)
+
+// loadWallet loads the wallet of the current profile, putting the default
+// skin on if the one it wears is not available.
+func loadWallet() *storage.Wallet {
+	w := storage.LoadWallet()
+	if w.Skin == "" || !owns(w, w.Skin) {
+		w.Skin = skins[0].id
+	}
+	return w
+}
+
+// owns reports whether the skin id is available in w, the free ones always
+// are.
+func owns(w *storage.Wallet, id string) bool {
+	s, ok := findSkin(id)
+	return ok && (s.price == 0 || slices.Contains(w.Skins, id))
+}
+
+// buy pays for the skin out of w and puts it on. It reports false when
+// there are not enough coins.
+func buy(w *storage.Wallet, s skin) bool {
+	if !owns(w, s.id) {
+		if w.Coins < s.price {
+			return false
+		}
+		w.Coins -= s.price
+		w.Skins = append(w.Skins, s.id)
+	}
+	w.Skin = s.id
+	return true
+}